@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"LC_utils/internal/api"
+)
+
+// parseAge parses a duration such as "30d", "2w", "36h" or "1d12h". On
+// top of the units of time.ParseDuration it accepts a leading number of
+// weeks (w) and days (d).
+func parseAge(value string) (time.Duration, error) {
+	rest := strings.TrimSpace(value)
+	var total time.Duration
+	for _, unit := range []struct {
+		suffix string
+		length time.Duration
+	}{{"w", 7 * 24 * time.Hour}, {"d", 24 * time.Hour}} {
+		i := strings.Index(rest, unit.suffix)
+		if i <= 0 {
+			continue
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		total += time.Duration(n) * unit.length
+		rest = rest[i+1:]
+	}
+	if rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid duration %q, use e.g. 30d, 2w or 12h", value)
+		}
+		total += d
+	}
+	if total == 0 && strings.TrimSpace(value) == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	return total, nil
+}
+
+// formatAge formats a duration in its largest whole unit: "45d", "3h",
+// "12m" or "30s".
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	default:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	}
+}
+
+// timeZone is the --tz flag; displayLocation is the location it names.
+var (
+	timeZone        string
+	displayLocation = time.UTC
+)
+
+// loadTimeZone resolves --tz: "UTC", "local" or an IANA name such as
+// "Europe/Paris".
+func loadTimeZone() error {
+	if strings.EqualFold(timeZone, "local") {
+		displayLocation = time.Local
+		return nil
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return fmt.Errorf("--tz: unknown time zone %q, use UTC, local or an IANA name such as Europe/Paris", timeZone)
+	}
+	displayLocation = loc
+	return nil
+}
+
+// formatRelative describes t relative to now, e.g. "3h ago".
+func formatRelative(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < -time.Minute:
+		return "in " + formatAge(-d)
+	case d < time.Minute:
+		return "just now"
+	default:
+		return formatAge(d) + " ago"
+	}
+}
+
+// formatSensorTime formats a sensor time for text output, in the --tz
+// time zone and followed by its age, e.g. "2026-01-02 15:04:05 UTC (3h
+// ago)". Unparsed times are shown as returned by the API.
+//
+// Parameters:
+//   - t: The parsed time
+//   - ok: Whether the time could be parsed
+//   - raw: The time as returned by the API
+//
+// Returns:
+//   - string: The formatted time, or "Never" if raw is empty
+func formatSensorTime(t time.Time, ok bool, raw string) string {
+	if !ok {
+		if raw == "" {
+			return "Never"
+		}
+		return raw
+	}
+	return fmt.Sprintf("%s (%s)", t.In(displayLocation).Format("2006-01-02 15:04:05 MST"), formatRelative(t, time.Now()))
+}
+
+// timeLayouts are the date and time formats accepted by the time
+// filters, interpreted in the --tz time zone unless they carry a zone.
+var timeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+// parseTimeSpec parses a point in time given either as an age relative
+// to now ("30d", "12h") or as a date or time ("2026-01-01",
+// "2026-01-01 08:00", RFC 3339).
+func parseTimeSpec(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, displayLocation); err == nil {
+			return t, nil
+		}
+	}
+	if d, err := parseAge(value); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use a date such as 2026-01-01, a time such as \"2026-01-01 08:00\" or an age such as 30d", value)
+}
+
+// lastSeenText formats the last seen time of a sensor for text output.
+func lastSeenText(sensor api.Sensor) string {
+	t, ok := sensor.LastSeenTime()
+	return formatSensorTime(t, ok, sensor.LastSeen)
+}
+
+// enrolledText formats the enrollment time of a sensor for text output.
+func enrolledText(sensor api.Sensor) string {
+	t, ok := sensor.EnrolledTime()
+	return formatSensorTime(t, ok, sensor.EnrollmentTime)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/auth"
+	"LC_utils/internal/fanout"
+
+	"github.com/fatih/color"
+)
+
+// Exit codes returned by lc-sensors, one per error class.
+const (
+	exitOK          = 0
+	exitError       = 1   // Any other failure
+	exitAuth        = 3   // Credentials rejected or JWT refused (401)
+	exitForbidden   = 4   // API key lacks a required permission (403)
+	exitNotFound    = 5   // Organization, sensor or resource not found (404)
+	exitRateLimited = 6   // Still rate limited after retries (429)
+	exitServer      = 7   // LimaCharlie API server error (5xx)
+	exitTimeout     = 124 // --timeout deadline exceeded
+	exitInterrupted = 130 // Interrupted with Ctrl-C
+)
+
+// exitCode maps an error to the exit code of its class.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, auth.ErrJWTExpired), errors.Is(err, api.ErrUnauthorized):
+		return exitAuth
+	case errors.Is(err, api.ErrForbidden):
+		return exitForbidden
+	case errors.Is(err, api.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, api.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, api.ErrServer):
+		return exitServer
+	default:
+		return exitError
+	}
+}
+
+// errorGuidance returns a hint on how to resolve err, or an empty string
+// when there is nothing more specific to say than the error itself.
+func errorGuidance(err error) string {
+	var apiErr *api.APIError
+	errors.As(err, &apiErr)
+
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return "Authentication failed. Please check your API key and organization ID."
+	case errors.Is(err, auth.ErrJWTExpired):
+		return "The JWT in LC_JWT has expired or was rejected. Obtain a new token or use an API key instead."
+	case errors.Is(err, api.ErrUnauthorized):
+		return "The API rejected the access token. Please check that the API key is still valid."
+	case errors.Is(err, api.ErrForbidden):
+		if apiErr != nil && apiErr.Permission != "" {
+			return fmt.Sprintf("The API key is missing the %q permission required for this operation.", apiErr.Permission)
+		}
+		return "The API key does not have permission for this operation."
+	case errors.Is(err, api.ErrNotFound):
+		return "The organization or sensor was not found. Please check the organization ID and sensor ID."
+	case errors.Is(err, api.ErrRateLimited):
+		return "The API is still rate limiting requests. Try again later or lower --concurrency/--rate."
+	case errors.Is(err, api.ErrServer):
+		return "The LimaCharlie API returned a server error. Try again later."
+	}
+	return ""
+}
+
+// fatal prints msg and err, followed by guidance for the error's class,
+// and exits with the class's exit code.
+func fatal(msg string, err error) {
+	color.Red("%s: %v", msg, err)
+	if hint := errorGuidance(err); hint != "" {
+		color.Yellow(hint)
+	}
+	os.Exit(exitCode(err))
+}
+
+// resultsExitCode returns the exit code for a fan-out run: exitOK if no
+// sensor failed, the failures' common class if they all share one, and
+// exitError otherwise.
+func resultsExitCode(ctx context.Context, results []fanout.Result) int {
+	code := exitOK
+	for _, r := range results {
+		if !r.Started || r.Err == nil || (ctx.Err() != nil && errors.Is(r.Err, ctx.Err())) {
+			continue
+		}
+		c := exitCode(r.Err)
+		if code != exitOK && code != c {
+			return exitError
+		}
+		code = c
+	}
+	return code
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/fanout"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Fan-out flags shared by the bulk sensor commands
+	concurrency int
+	rate        float64
+
+	// outputMu serializes console output from concurrent workers
+	outputMu sync.Mutex
+
+	// requestLimiter applies --rate to the requests of every API client
+	requestLimiter     *fanout.Limiter
+	requestLimiterOnce sync.Once
+)
+
+// addFanOutFlags registers the --concurrency and --rate flags on a bulk
+// command.
+func addFanOutFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&concurrency, "concurrency", 10, "Number of sensors processed in parallel")
+	cmd.Flags().Float64Var(&rate, "rate", 0, "Maximum API requests per second across all workers, retries included (0 = unlimited)")
+}
+
+// apiRateLimiter returns the limiter shared by every API client, or nil
+// when --rate is not set. The API clients wait on it before each request,
+// retries included, so worker pools need no rate limit of their own.
+func apiRateLimiter() *fanout.Limiter {
+	requestLimiterOnce.Do(func() {
+		requestLimiter = fanout.NewLimiter(rate)
+	})
+	return requestLimiter
+}
+
+// runOnSensors calls fn for every selected sensor using a worker pool of
+// --concurrency workers. The sensor's organization is passed to fn so
+// that it can use its client and prefix output lines. Sensors of every
+// organization share the pool. Results are returned in the same order as
+// sensors.
+func runOnSensors(ctx context.Context, sensors []targetSensor, fn func(ctx context.Context, org *orgClient, sensor api.Sensor) error) []fanout.Result {
+	pool := fanout.New(concurrency, 0)
+	return pool.Run(ctx, len(sensors), func(ctx context.Context, i int) error {
+		return fn(ctx, sensors[i].org, sensors[i].sensor)
+	}, nil)
+}
+
+// printLocked runs f while holding the output lock so that lines printed
+// by concurrent workers do not interleave.
+func printLocked(f func()) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	f()
+}
+
+// countSkipped returns the number of sensors that were not processed, or
+// only partially processed, because ctx was cancelled.
+func countSkipped(ctx context.Context, results []fanout.Result) int {
+	if ctx.Err() == nil {
+		return 0
+	}
+	skipped := 0
+	for _, r := range results {
+		if !r.Started || errors.Is(r.Err, ctx.Err()) {
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// printFailedSensors lists, in selection order, the sensors whose work
+// function returned an error other than a cancellation.
+func printFailedSensors(ctx context.Context, sensors []targetSensor, results []fanout.Result) {
+	var lines []string
+	for _, r := range results {
+		if !r.Started || r.Err == nil || (ctx.Err() != nil && errors.Is(r.Err, ctx.Err())) {
+			continue
+		}
+		ts := sensors[r.Index]
+		lines = append(lines, fmt.Sprintf("- %s%s (%s): %v", ts.org.prefix(), ts.sensor.Hostname, ts.sensor.SID, r.Err))
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	color.Red("\nFailed sensors:")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
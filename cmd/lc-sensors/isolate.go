@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"LC_utils/internal/api"
+
+	"github.com/spf13/cobra"
+)
+
+// newIsolateCmd returns the `isolate` command.
+func newIsolateCmd() *cobra.Command {
+	return newStateChangeCmd(&cobra.Command{
+		Use:   "isolate",
+		Short: "Isolate sensors matching filters from the network",
+		Long: `Isolate sensors that match the specified filters from the network. Isolated
+sensors keep their connection to LimaCharlie only.
+
+Example:
+  # Isolate the sensors tagged "compromised"
+  lc-sensors isolate --filter-tag compromised`,
+	}, sensorStateChange{
+		verb:       "isolate",
+		done:       "isolated",
+		skipReason: "already isolated",
+		want:       true,
+		current:    isIsolated,
+		apply: func(ctx context.Context, client *api.Client, sensorID string) error {
+			return client.IsolateSensor(ctx, sensorID)
+		},
+	})
+}
+
+// newRejoinCmd returns the `rejoin` command.
+func newRejoinCmd() *cobra.Command {
+	return newStateChangeCmd(&cobra.Command{
+		Use:   "rejoin",
+		Short: "Rejoin isolated sensors matching filters to the network",
+		Long: `Rejoin isolated sensors that match the specified filters to the network.
+
+Example:
+  # Rejoin the isolated Windows sensors with hostname matching "web-*"
+  lc-sensors rejoin --filter-platform windows --filter-hostname "web-*"`,
+	}, sensorStateChange{
+		verb:       "rejoin",
+		done:       "rejoined",
+		skipReason: "not isolated",
+		want:       false,
+		current:    isIsolated,
+		apply: func(ctx context.Context, client *api.Client, sensorID string) error {
+			return client.RejoinSensor(ctx, sensorID)
+		},
+	})
+}
+
+// isIsolated reads the isolation state of a sensor.
+func isIsolated(sensor api.Sensor) bool {
+	return sensor.IsIsolated
+}
+
+// newStateChangeCmd completes cmd as a command applying change to the
+// sensors selected by the selection flags.
+func newStateChangeCmd(cmd *cobra.Command, change sensorStateChange) *cobra.Command {
+	// Every state change is verified the same way, so describe it once
+	if before, after, ok := strings.Cut(cmd.Long, "\n\nExample:"); ok {
+		cmd.Long = before + "\n\n" + verifyHelp + "\n\nExample:" + after
+	} else {
+		cmd.Long += "\n\n" + verifyHelp
+	}
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := requireCredentials(); err != nil {
+			return err
+		}
+		if err := validateVerifyFlags(); err != nil {
+			return err
+		}
+		return validateSelection()
+	}
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		runStateChange(cmd, change)
+	}
+
+	addSelectionFlags(cmd)
+	addVerifyFlags(cmd)
+	addFanOutFlags(cmd)
+	addOrgFlags(cmd)
+	return cmd
+}
@@ -1,33 +1,59 @@
 package main
 
 import (
-	"encoding/csv"
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"math/rand"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"LC_utils/internal/api"
 	"LC_utils/internal/auth"
+	"LC_utils/internal/fanout"
+	"LC_utils/internal/logging"
 
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
+// version is reported in the banner and the API user agent
+const version = "1.0.0"
+
 var (
 	// Global flags
-	oid    string // Organization ID from flag
-	apiKey string // API Key from flag
-	action string
-	fun    bool
-	matrix bool
-	hack   bool
-	theme  string
+	oid         string // Organization ID from flag
+	apiKey      string // API Key from flag
+	apiKeyFile  string // File holding the API key
+	userID      string // User ID of a user API key
+	userJWT     string // Pre-issued JWT from LC_JWT
+	apiEndpoint string // API base URL override
+	jwtEndpoint string // JWT endpoint override
+	timeout     time.Duration
+	maxRetries  int
+	retryDelay  time.Duration
+	logLevel    string // Minimum level of log records written to stderr
+	logFormat   string // text or json
+	logSecrets  bool   // Disable redaction of secrets in logs
+	httpTrace   bool   // Log every HTTP request and response
+	action      string
+	fun         bool
+	matrix      bool
+	hack        bool
+	theme       string
+
+	// cancelTimeout releases the --timeout deadline once the command returns
+	cancelTimeout context.CancelFunc = func() {}
 
 	// Theme colors (package level)
 	blue  = "\x1b[34m"
@@ -45,6 +71,11 @@ var (
 	filterHostname string
 	filterTag      string
 	onlineOnly     bool
+	filterSealed   bool
+	filterUnsealed bool
+
+	// Get action flags
+	getOutput string
 
 	// Tag action flags
 	sensorID   string
@@ -111,17 +142,23 @@ func init() {
 	// Force color output
 	color.NoColor = false
 
-	// Check environment variables first
-	if envOID := os.Getenv("LC_ORG_ID"); envOID != "" {
-		oid = envOID
-	}
-	if envAPIKey := os.Getenv("LC_API_KEY"); envAPIKey != "" {
-		apiKey = envAPIKey
-	}
-
-	// Global flags
-	rootCmd.PersistentFlags().StringVarP(&oid, "oid", "o", oid, "LimaCharlie Organization ID (required)")
-	rootCmd.PersistentFlags().StringVarP(&apiKey, "api-key", "k", apiKey, "LimaCharlie API Key (required)")
+	// Global flags; environment variables and profiles are applied in
+	// resolveSettings once the flags are parsed
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Config profile to use (default: the current profile, or LC_PROFILE)")
+	rootCmd.PersistentFlags().StringVarP(&oid, "oid", "o", "", "LimaCharlie Organization ID (or LC_ORG_ID)")
+	rootCmd.PersistentFlags().StringVarP(&apiKey, "api-key", "k", "", "LimaCharlie API Key (or LC_API_KEY); prefer --api-key-file, which keeps it out of shell history")
+	rootCmd.PersistentFlags().StringVar(&apiKeyFile, "api-key-file", "", "File holding the LimaCharlie API Key; must not be readable by all users")
+	rootCmd.PersistentFlags().StringVar(&userID, "uid", "", "User ID when --api-key is a user API key (or LC_UID)")
+	rootCmd.PersistentFlags().StringVar(&apiEndpoint, "endpoint", "", "LimaCharlie API base URL (default "+api.DefaultBaseURL+")")
+	rootCmd.PersistentFlags().StringVar(&jwtEndpoint, "jwt-endpoint", "", "LimaCharlie JWT endpoint (default "+auth.DefaultJWTEndpoint+")")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Abort the command after this duration, e.g. 30s or 10m (0 disables)")
+	rootCmd.PersistentFlags().IntVar(&maxRetries, "max-retries", api.DefaultRetryPolicy().MaxRetries, "Retries per API request on rate limiting (429) and server errors (5xx); 0 disables")
+	rootCmd.PersistentFlags().DurationVar(&retryDelay, "retry-max-delay", api.DefaultRetryPolicy().MaxDelay, "Maximum wait between retries, including Retry-After")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "Log level for messages written to stderr (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format (text, json)")
+	rootCmd.PersistentFlags().BoolVar(&httpTrace, "http-trace", false, "Log every HTTP request and response, with secrets redacted")
+	rootCmd.PersistentFlags().BoolVar(&logSecrets, "log-show-secrets", false, "Do not redact API keys, JWTs and passwords in logs (unsafe)")
+	rootCmd.PersistentFlags().StringVar(&timeZone, "tz", "UTC", "Time zone of times in text output (UTC, local or an IANA name such as Europe/Paris)")
 	rootCmd.Flags().BoolVar(&fun, "fun", false, "Just show the cool banner")
 	rootCmd.Flags().BoolVar(&matrix, "matrix", false, "Show Matrix-style animation")
 	rootCmd.Flags().BoolVar(&hack, "hack", false, "Show hacking animation")
@@ -137,14 +174,13 @@ func init() {
 		Use:   "list",
 		Short: "List and filter sensors",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			// Use environment variables if available, otherwise use flags
-			if oid == "" {
-				return fmt.Errorf("organization ID is required (set via --oid flag or LC_ORG_ID environment variable)")
+			if err := requireCredentials(); err != nil {
+				return err
 			}
-			if apiKey == "" {
-				return fmt.Errorf("API key is required (set via --api-key flag or LC_API_KEY environment variable)")
+			if filterSealed && filterUnsealed {
+				return fmt.Errorf("--filter-sealed and --filter-unsealed cannot be used together")
 			}
-			return nil
+			return prepareFilters()
 		},
 		Run: runList,
 	}
@@ -155,21 +191,53 @@ func init() {
 	listCmd.Flags().BoolVarP(&withTags, "tags", "t", false, "Include sensor tags in output")
 	listCmd.Flags().StringVarP(&withIP, "ip", "i", "", "Filter sensors by IP address")
 	listCmd.Flags().StringVarP(&hostnamePrefix, "hostname", "n", "", "Filter sensors by hostname prefix")
-	listCmd.Flags().StringVar(&filterHostname, "filter-hostname", "", "Filter by hostname (supports wildcards *)")
-	listCmd.Flags().StringVar(&filterPlatform, "filter-platform", "", "Filter by platform (windows, macos)")
-	listCmd.Flags().StringVar(&filterTag, "filter-tag", "", "Filter by tag")
+	listCmd.Flags().StringVar(&filterHostname, "filter-hostname", "", hostnameFilterUsage)
+	listCmd.Flags().StringVar(&filterPlatform, "filter-platform", "", platformFilterUsage)
+	listCmd.Flags().StringVar(&filterTag, "filter-tag", "", tagFilterUsage)
+	listCmd.Flags().BoolVar(&filterRegex, "regex", false, regexFilterUsage)
 	listCmd.Flags().BoolVar(&onlineOnly, "online", false, "Show only online sensors")
+	listCmd.Flags().BoolVar(&filterSealed, "filter-sealed", false, "Show only sealed sensors")
+	listCmd.Flags().BoolVar(&filterUnsealed, "filter-unsealed", false, "Show only sensors that are not sealed")
+	addTimeFilterFlags(listCmd)
+	addAddrFilterFlags(listCmd)
+	addSelectFlag(listCmd)
+	addSidsFlags(listCmd)
+	addOrgFlags(listCmd)
+
+	// Get command
+	var getCmd = &cobra.Command{
+		Use:   "get <sid|hostname|sid-prefix>",
+		Short: "Show every detail of a single sensor",
+		Long: `Show every detail of a single sensor, including its isolation and seal state,
+kernel availability, installer version and MAC address.
+
+The sensor can be given by its SID, its hostname (case-insensitive) or a
+unique prefix of its SID.
+
+Example:
+  lc-sensors get web-01 -f yaml`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireCredentials(); err != nil {
+				return err
+			}
+			switch getOutput {
+			case "text", "json", "yaml":
+				return nil
+			}
+			return fmt.Errorf("--output must be one of: text, json, yaml")
+		},
+		Run: runGet,
+	}
+	getCmd.Flags().StringVarP(&getOutput, "output", "f", "text", "Output format (text/json/yaml)")
 
 	// Tag command
 	var tagCmd = &cobra.Command{
 		Use:   "tag",
 		Short: "Add or remove tags from a sensor",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if oid == "" {
-				return fmt.Errorf("organization ID is required (set via --oid flag or LC_ORG_ID environment variable)")
-			}
-			if apiKey == "" {
-				return fmt.Errorf("API key is required (set via --api-key flag or LC_API_KEY environment variable)")
+			if err := requireCredentials(); err != nil {
+				return err
 			}
 			if sensorID == "" {
 				return fmt.Errorf("--sensor-id is required")
@@ -197,25 +265,29 @@ Example:
   # Tag all Windows sensors with hostname matching "*web*"
   lc-sensors tag-multiple -o ORG_ID -k API_KEY --filter-platform windows --filter-hostname "*web*" --add-tags web-server`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if oid == "" {
-				return fmt.Errorf("organization ID is required (set via --oid flag or LC_ORG_ID environment variable)")
-			}
-			if apiKey == "" {
-				return fmt.Errorf("API key is required (set via --api-key flag or LC_API_KEY environment variable)")
+			if err := requireCredentials(); err != nil {
+				return err
 			}
 			if len(addTags) == 0 && len(removeTags) == 0 {
 				return fmt.Errorf("at least one of --add-tags or --remove-tags must be specified")
 			}
-			return nil
+			return prepareFilters()
 		},
 		Run: runTagMultiple,
 	}
 
 	// Tag-multiple command flags
-	tagMultipleCmd.Flags().StringVar(&filterHostname, "filter-hostname", "", "Filter by hostname (supports wildcards *)")
-	tagMultipleCmd.Flags().StringVar(&filterPlatform, "filter-platform", "", "Filter by platform (windows, macos)")
+	tagMultipleCmd.Flags().StringVar(&filterHostname, "filter-hostname", "", hostnameFilterUsage)
+	tagMultipleCmd.Flags().BoolVar(&filterRegex, "regex", false, regexFilterUsage)
+	tagMultipleCmd.Flags().StringVar(&filterPlatform, "filter-platform", "", platformFilterUsage)
 	tagMultipleCmd.Flags().StringSliceVar(&addTags, "add-tags", []string{}, "Tags to add (comma-separated)")
 	tagMultipleCmd.Flags().StringSliceVar(&removeTags, "remove-tags", []string{}, "Tags to remove (comma-separated)")
+	addTimeFilterFlags(tagMultipleCmd)
+	addAddrFilterFlags(tagMultipleCmd)
+	addSelectFlag(tagMultipleCmd)
+	addSidsFlags(tagMultipleCmd)
+	addFanOutFlags(tagMultipleCmd)
+	addOrgFlags(tagMultipleCmd)
 
 	// Task command
 	var taskCmd = &cobra.Command{
@@ -238,14 +310,11 @@ Example:
   # Upload a file to all Windows sensors with hostname matching "web-*"
   lc-sensors task put -o ORG_ID -k API_KEY --filter-platform windows --filter-hostname "web-*" --payload-name file.txt --payload-path "/tmp/file.txt"`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if oid == "" {
-				return fmt.Errorf("organization ID is required (set via --oid flag or LC_ORG_ID environment variable)")
-			}
-			if apiKey == "" {
-				return fmt.Errorf("API key is required (set via --api-key flag or LC_API_KEY environment variable)")
+			if err := requireCredentials(); err != nil {
+				return err
 			}
-			if filterHostname == "" && filterTag == "" {
-				return fmt.Errorf("either --filter-hostname or --filter-tag is required")
+			if err := requireTargetFilter(); err != nil {
+				return err
 			}
 			if taskCommandList == "" {
 				if taskPayloadName == "" {
@@ -255,15 +324,16 @@ Example:
 					return fmt.Errorf("--payload-path is required when not using --command-list")
 				}
 			}
-			return nil
+			return prepareFilters()
 		},
 		Run: runPutTask,
 	}
 
 	// Put command flags
-	putCmd.PersistentFlags().StringVar(&filterHostname, "filter-hostname", "", "Filter sensors by hostname (supports wildcards *)")
-	putCmd.PersistentFlags().StringVar(&filterTag, "filter-tag", "", "Filter sensors by tag (supports wildcards *)")
-	putCmd.PersistentFlags().StringVar(&filterPlatform, "filter-platform", "", "Filter by platform (windows, macos, linux)")
+	putCmd.PersistentFlags().StringVar(&filterHostname, "filter-hostname", "", hostnameFilterUsage)
+	putCmd.PersistentFlags().StringVar(&filterTag, "filter-tag", "", tagFilterUsage)
+	putCmd.PersistentFlags().BoolVar(&filterRegex, "regex", false, regexFilterUsage)
+	putCmd.PersistentFlags().StringVar(&filterPlatform, "filter-platform", "", platformFilterUsage)
 	putCmd.PersistentFlags().StringVar(&taskPayloadName, "payload-name", "", "Name of the payload file (required)")
 	putCmd.PersistentFlags().StringVar(&taskPayloadPath, "payload-path", "", "Path on the sensor where to write the file (required)")
 	putCmd.PersistentFlags().StringVar(&taskCommandList, "command-list", "", "Path to a file containing commands to execute (one per line)")
@@ -272,6 +342,12 @@ Example:
 	putCmd.PersistentFlags().BoolVar(&taskReliable, "reliable", false, "Use reliable tasking (will retry if sensor is offline)")
 	putCmd.PersistentFlags().StringVar(&taskContext, "context", "", "Context value for reliable tasking (only used with --reliable)")
 	putCmd.PersistentFlags().Int64Var(&taskTTL, "ttl", 604800, "Time-to-live in seconds for reliable tasking (default 1 week, only used with --reliable)")
+	addTimeFilterFlags(putCmd)
+	addAddrFilterFlags(putCmd)
+	addSelectFlag(putCmd)
+	addSidsFlags(putCmd)
+	addFanOutFlags(putCmd)
+	addOrgFlags(putCmd)
 
 	// Run command
 	var runCmd = &cobra.Command{
@@ -292,14 +368,11 @@ Example:
   # Run multiple commands from a file with random delay on tagged sensors
   lc-sensors task run -o ORG_ID -k API_KEY --filter-tag "web-server" --command-list commands.txt --random-delay --reliable`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if oid == "" {
-				return fmt.Errorf("--oid is required")
-			}
-			if apiKey == "" {
-				return fmt.Errorf("--api-key is required")
+			if err := requireCredentials(); err != nil {
+				return err
 			}
-			if filterHostname == "" && filterTag == "" {
-				return fmt.Errorf("either --filter-hostname or --filter-tag is required")
+			if err := requireTargetFilter(); err != nil {
+				return err
 			}
 			if taskCommand == "" && taskCommandList == "" {
 				return fmt.Errorf("either --command or --command-list is required")
@@ -307,23 +380,16 @@ Example:
 			if taskCommand != "" && taskCommandList != "" {
 				return fmt.Errorf("cannot use both --command and --command-list")
 			}
-			// Validate platform value if provided
-			if filterPlatform != "" {
-				platform := strings.ToLower(filterPlatform)
-				if platform != "windows" && platform != "macos" && platform != "linux" {
-					return fmt.Errorf("--filter-platform must be one of: windows, macos, linux")
-				}
-				filterPlatform = platform // Store normalized value
-			}
-			return nil
+			return prepareFilters()
 		},
 		Run: runRunTask,
 	}
 
 	// Run command flags
-	runCmd.Flags().StringVar(&filterHostname, "filter-hostname", "", "Filter sensors by hostname (supports wildcards *)")
-	runCmd.Flags().StringVar(&filterTag, "filter-tag", "", "Filter sensors by tag (supports wildcards *)")
-	runCmd.Flags().StringVar(&filterPlatform, "filter-platform", "", "Filter by platform (windows, macos, linux)")
+	runCmd.Flags().StringVar(&filterHostname, "filter-hostname", "", hostnameFilterUsage)
+	runCmd.Flags().StringVar(&filterTag, "filter-tag", "", tagFilterUsage)
+	runCmd.Flags().BoolVar(&filterRegex, "regex", false, regexFilterUsage)
+	runCmd.Flags().StringVar(&filterPlatform, "filter-platform", "", platformFilterUsage)
 	runCmd.Flags().StringVar(&taskCommand, "command", "", "Command to execute (required if --command-list not specified)")
 	runCmd.Flags().StringVar(&taskCommandList, "command-list", "", "Path to a file containing commands to execute (one per line)")
 	runCmd.Flags().BoolVar(&taskRandomDelay, "random-delay", false, "Add random delay between commands (5-15 seconds)")
@@ -331,6 +397,12 @@ Example:
 	runCmd.Flags().BoolVar(&taskReliable, "reliable", false, "Use reliable tasking (will retry if sensor is offline)")
 	runCmd.Flags().StringVar(&taskContext, "context", "", "Context value for reliable tasking (only used with --reliable)")
 	runCmd.Flags().Int64Var(&taskTTL, "ttl", 604800, "Time-to-live in seconds for reliable tasking (default 1 week, only used with --reliable)")
+	addTimeFilterFlags(runCmd)
+	addAddrFilterFlags(runCmd)
+	addSelectFlag(runCmd)
+	addSidsFlags(runCmd)
+	addFanOutFlags(runCmd)
+	addOrgFlags(runCmd)
 
 	// Add commands to task
 	taskCmd.AddCommand(putCmd)
@@ -338,10 +410,22 @@ Example:
 
 	// Add all commands to root
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(getCmd)
 	rootCmd.AddCommand(tagCmd)
 	rootCmd.AddCommand(tagMultipleCmd)
 	rootCmd.AddCommand(taskCmd)
+	rootCmd.AddCommand(newIsolateCmd())
+	rootCmd.AddCommand(newRejoinCmd())
+	rootCmd.AddCommand(newSealCmd())
+	rootCmd.AddCommand(newUnsealCmd())
+	rootCmd.AddCommand(newPruneCmd())
+	rootCmd.AddCommand(newSubnetsCmd())
+	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newSnapshotCmd())
+	rootCmd.AddCommand(newWatchCmd())
 	rootCmd.AddCommand(uploadPayloadsCmd)
+	rootCmd.AddCommand(newProfileCmd())
+	rootCmd.AddCommand(newOrgsCmd())
 }
 
 func getRandomMessage() string {
@@ -437,7 +521,7 @@ func printBanner() string {
 
 	// Add version and status with colors
 	sb.WriteString(fmt.Sprintf("%s[%s", blue, reset))
-	sb.WriteString(fmt.Sprintf("%sv1.0.0%s", white, reset))
+	sb.WriteString(fmt.Sprintf("%sv%s%s", white, version, reset))
 	sb.WriteString(fmt.Sprintf("%s] %s", blue, reset))
 	sb.WriteString(fmt.Sprintf("%sSystem Status: %s", cyan, reset))
 	sb.WriteString(fmt.Sprintf("%sOPERATIONAL%s", white, reset))
@@ -569,18 +653,131 @@ func printHackingAnimation() {
 	time.Sleep(1 * time.Second)
 }
 
+// newAPIClient creates an API client from the global credential and
+// endpoint flags and validates the credentials against the JWT endpoint.
+func newAPIClient(ctx context.Context) (*api.Client, error) {
+	return newAPIClientFor(ctx, currentTarget())
+}
+
+// newAPIClientFor creates an API client for one organization and
+// validates its credentials against the JWT endpoint.
+func newAPIClientFor(ctx context.Context, target orgTarget) (*api.Client, error) {
+	client, err := newUserClient(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if client.OID() == "" {
+		return nil, fmt.Errorf("organization ID is required (set via --oid flag, LC_ORG_ID environment variable or a profile)")
+	}
+	return client, nil
+}
+
+// newUserClient creates and validates an API client for target without
+// requiring an organization ID, as needed for user-level calls. The
+// credentials are a pre-issued JWT if one is set, else a user API key
+// if a user ID is set, else an organization API key.
+func newUserClient(ctx context.Context, target orgTarget) (*api.Client, error) {
+	var creds *auth.Credentials
+	switch {
+	case target.JWT != "":
+		creds = auth.NewJWTCredentials(target.OID, target.JWT)
+	case target.UID != "":
+		creds = auth.NewUserCredentials(target.UID, target.APIKey, target.OID)
+	default:
+		creds = auth.NewCredentials(target.OID, target.APIKey)
+	}
+	retryPolicy := api.DefaultRetryPolicy()
+	retryPolicy.MaxRetries = maxRetries
+	retryPolicy.MaxDelay = retryDelay
+	opts := []api.Option{
+		api.WithBaseURL(target.Endpoint),
+		api.WithJWTEndpoint(target.JWTEndpoint),
+		api.WithUserAgent("lc-sensors/" + version),
+		api.WithRetryPolicy(retryPolicy),
+		api.WithLogger(slog.Default()),
+	}
+	if httpTrace {
+		opts = append(opts, api.WithHTTPTrace(logSecrets))
+	}
+	if limiter := apiRateLimiter(); limiter != nil {
+		opts = append(opts, api.WithRateLimiter(limiter))
+	}
+	client := api.NewClient(creds, opts...)
+
+	// Validate credentials
+	if err := creds.ValidateCredentials(ctx); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	// The first interrupt cancels in-flight work so commands can still
+	// print their summary; a second one terminates immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	if err != nil {
 		color.Red("Error: %v", err)
-		os.Exit(1)
+		if hint := errorGuidance(err); hint != "" {
+			color.Yellow(hint)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "lc-sensors",
 	Short: "LimaCharlie Sensor Management Tool",
-	Long:  `A CLI tool for managing LimaCharlie sensors and related functionality.`,
+	Long: `A CLI tool for managing LimaCharlie sensors and related functionality.
+
+Exit codes:
+  0    Success
+  1    General failure
+  3    Authentication failed (invalid API key or organization ID)
+  4    Permission denied (the API key lacks a required permission)
+  5    Organization, sensor or resource not found
+  6    Rate limited after all retries
+  7    LimaCharlie API server error
+  124  Timed out (--timeout)
+  130  Interrupted (Ctrl-C)`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Logs go to stderr so they never mix with JSON or CSV output
+		logger, err := logging.New(os.Stderr, logging.Options{
+			Level:       logLevel,
+			Format:      logFormat,
+			ShowSecrets: logSecrets,
+			HTTPTrace:   httpTrace,
+		})
+		if err != nil {
+			fatal("Error", err)
+		}
+		slog.SetDefault(logger)
+
+		if err := loadTimeZone(); err != nil {
+			fatal("Error", err)
+		}
+
+		// Apply the environment and the selected profile beneath the flags
+		if needsProfile(cmd) {
+			if err := resolveSettings(cmd); err != nil {
+				fatal("Error", err)
+			}
+		}
+
+		// Bound the whole command by --timeout
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(ctx)
+			cancelTimeout = cancel
+		}
+
 		// Environment variables are already handled in init()
 		// Apply theme to all color output
 		if theme != "" {
@@ -625,8 +822,15 @@ var uploadPayloadsCmd = &cobra.Command{
 This command will recursively search for .exe files in the specified directory
 and upload them as payloads to your LimaCharlie organization.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if oid == "" || apiKey == "" {
-			return fmt.Errorf("organization ID and API key are required")
+		ctx := cmd.Context()
+		if err := requireCredentials(); err != nil {
+			return err
+		}
+
+		// Initialize API client
+		client, err := newAPIClient(ctx)
+		if err != nil {
+			return err
 		}
 
 		// Find all executable files
@@ -642,12 +846,23 @@ and upload them as payloads to your LimaCharlie organization.`,
 
 		// Process each file
 		results := make(map[string]string)
-		for _, file := range files {
+		skipped := 0
+		for i, file := range files {
+			if ctx.Err() != nil {
+				skipped = len(files) - i
+				break
+			}
+
 			relPath, _ := filepath.Rel(basePath, file)
 			fmt.Printf("Processing %s... ", relPath)
 
-			err := api.UploadPayload(oid, apiKey, file)
-			if err != nil {
+			err := client.UploadPayload(ctx, file)
+			if err != nil && ctx.Err() != nil {
+				// Interrupted mid-upload; report it with the skipped files
+				color.Yellow("Cancelled")
+				skipped = len(files) - i
+				break
+			} else if err != nil {
 				results[relPath] = fmt.Sprintf("Error: %v", err)
 				color.Red("Failed")
 			} else {
@@ -673,68 +888,223 @@ and upload them as payloads to your LimaCharlie organization.`,
 			return fmt.Errorf("unsupported output format: %s", outputFmt)
 		}
 
+		printRetrySummary(client)
+		if skipped > 0 {
+			printCancelled(ctx, skipped, "files")
+			exitIfCancelled(ctx)
+		}
+
 		return nil
 	},
 }
 
 func runList(cmd *cobra.Command, args []string) {
-	// Print banner
-	fmt.Print(printBanner())
-
-	// Initialize credentials
-	creds := auth.NewCredentials(oid, apiKey)
+	ctx := cmd.Context()
 
-	// Validate credentials
-	if err := creds.ValidateCredentials(); err != nil {
-		color.Red("Error: %v", err)
-		os.Exit(1)
+	// Keep machine-readable output clean
+	text := output != "json" && output != "csv"
+	if text {
+		fmt.Print(printBanner())
 	}
 
-	color.Blue("Authenticating with LimaCharlie...")
+	// Initialize API clients, one per organization
+	orgs, exitStatus := connectOrgs(cmd)
+
+	if text {
+		color.Blue("Authenticating with LimaCharlie...")
+	}
 
 	// Prepare listing options
 	opts := &api.ListOptions{
 		Limit:              limit,
-		WithTags:           withTags || filterTag != "", // Always fetch tags if filtering by tag
+		WithTags:           withTags || needTags(), // Always fetch tags if filtering by tag
 		WithIP:             withIP,
 		WithHostnamePrefix: hostnamePrefix,
 		OnlyOnline:         onlineOnly,
 	}
 
-	// List sensors
-	color.Blue("Retrieving sensors...")
-	sensors, err := api.ListSensors(creds, opts)
+	// List sensors page by page, writing each one as soon as it is
+	// decoded. Organizations are listed in parallel and share the writer.
+	if text {
+		color.Green("Successfully authenticated with LimaCharlie!")
+		color.Blue("Retrieving sensors...")
+	}
+	writer := newSensorWriter(output, os.Stdout)
+	counts := make([]orgCounts, len(orgs))
+	pool := fanout.New(orgConcurrency, 0)
+	results := pool.Run(ctx, len(orgs), func(ctx context.Context, i int) error {
+		org := orgs[i]
+		return org.client.ForEachSensor(ctx, opts, func(sensor api.Sensor) error {
+			if !sensorMatchesFilters(sensor) {
+				return nil
+			}
+			var err error
+			printLocked(func() {
+				counts[i].selected++
+				err = writer.Write(org, sensor)
+			})
+			return err
+		})
+	}, nil)
+	closeErr := writer.Close()
+	if ctx.Err() == nil {
+		warnUnmatchedRefs()
+	}
+
+	if !multiOrg() {
+		if err := results[0].Err; err != nil {
+			if ctx.Err() != nil {
+				printCancelled(ctx, 0, "")
+				exitIfCancelled(ctx)
+			}
+			fatal("Failed to retrieve sensors", err)
+		}
+		if closeErr != nil {
+			fatal("Failed to write output", closeErr)
+		}
+		return
+	}
+
+	// Keep machine-readable output clean by sending the summary to stderr
+	for i, r := range results {
+		counts[i].err = r.Err
+	}
+	summaryOut := io.Writer(os.Stdout)
+	if !text {
+		summaryOut = os.Stderr
+	}
+	printOrgSummary(summaryOut, orgs, counts, false)
+	if closeErr != nil {
+		fatal("Failed to write output", closeErr)
+	}
+	printCancelled(ctx, 0, "")
+	exitIfCancelled(ctx)
+	if code := combineExitCodes(exitStatus, resultsExitCode(ctx, results)); code != exitOK {
+		os.Exit(code)
+	}
+}
+
+func runGet(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	// Keep machine-readable output clean
+	if getOutput == "text" {
+		fmt.Print(printBanner())
+	}
+
+	// Initialize API client
+	client, err := newAPIClient(ctx)
 	if err != nil {
-		if strings.Contains(strings.ToLower(err.Error()), "401") {
-			color.Red("Authentication failed. Please check your API key and organization ID.")
-		} else {
-			color.Red("Failed to retrieve sensors: %v", err)
+		fatal("Error", err)
+	}
+
+	sid, err := resolveSensorID(ctx, client, args[0])
+	if err != nil {
+		fatal("Failed to find sensor", err)
+	}
+	sensor, err := client.GetSensor(ctx, sid)
+	if err != nil {
+		fatal("Failed to retrieve sensor", err)
+	}
+
+	switch getOutput {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(sensor)
+	case "yaml":
+		err = writeYAML(os.Stdout, sensor)
+	default:
+		outputSensorText(*sensor)
+	}
+	if err != nil {
+		fatal("Failed to write output", err)
+	}
+}
+
+// resolveSensorID turns a SID, hostname or SID prefix into a SID. Full
+// SIDs are returned as is; anything else is looked up in the sensor
+// list, preferring exact (case-insensitive) hostname matches over SID
+// prefixes. It is an error if no sensor or more than one matches.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation
+//   - client: Client of the sensor's organization
+//   - ref: The SID, hostname or SID prefix
+//
+// Returns:
+//   - string: The sensor's SID
+//   - error: An error matching api.ErrNotFound if nothing matches, or
+//     listing the candidates if the reference is ambiguous
+func resolveSensorID(ctx context.Context, client *api.Client, ref string) (string, error) {
+	if isSensorID(ref) {
+		return ref, nil
+	}
+
+	var byHostname, byPrefix []api.Sensor
+	prefix := strings.ToLower(ref)
+	err := client.ForEachSensor(ctx, nil, func(sensor api.Sensor) error {
+		switch {
+		case strings.EqualFold(sensor.Hostname, ref):
+			byHostname = append(byHostname, sensor)
+		case strings.HasPrefix(strings.ToLower(sensor.SID), prefix):
+			byPrefix = append(byPrefix, sensor)
 		}
-		os.Exit(1)
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
-	color.Green("Successfully authenticated with LimaCharlie!")
+	matches := byHostname
+	if len(matches) == 0 {
+		matches = byPrefix
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: no sensor has the SID, hostname or SID prefix %q", api.ErrNotFound, ref)
+	case 1:
+		return matches[0].SID, nil
+	}
 
-	// Apply all filters
-	if filterHostname != "" || filterPlatform != "" || filterTag != "" {
-		sensors = filterSensors(sensors, nil)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%q matches %d sensors, use a SID instead:", ref, len(matches))
+	for _, sensor := range matches {
+		fmt.Fprintf(&sb, "\n- %s (%s)", sensor.Hostname, sensor.SID)
 	}
+	return "", errors.New(sb.String())
+}
 
-	// Output results based on format
-	outputResults(sensors)
+// isSensorID reports whether s has the form of a full SID, a UUID.
+func isSensorID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 func runTag(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
 	// Print banner
 	fmt.Print(printBanner())
 
-	// Initialize credentials
-	creds := auth.NewCredentials(oid, apiKey)
-
-	// Validate credentials
-	if err := creds.ValidateCredentials(); err != nil {
-		color.Red("Error: %v", err)
-		os.Exit(1)
+	// Initialize API client
+	client, err := newAPIClient(ctx)
+	if err != nil {
+		fatal("Error", err)
 	}
 
 	if len(addTags) == 0 && len(removeTags) == 0 {
@@ -743,12 +1113,11 @@ func runTag(cmd *cobra.Command, args []string) {
 	}
 
 	// Tag a single sensor
-	if err := api.TagSensor(creds, sensorID, api.TagSensorRequest{
+	if err := client.TagSensor(ctx, sensorID, api.TagSensorRequest{
 		AddTags:    addTags,
 		RemoveTags: removeTags,
 	}); err != nil {
-		color.Red("Failed to tag sensor: %w", err)
-		os.Exit(1)
+		fatal("Failed to tag sensor", err)
 	}
 
 	// Success message
@@ -762,17 +1131,13 @@ func runTag(cmd *cobra.Command, args []string) {
 }
 
 func runTagMultiple(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
 	// Print banner
 	fmt.Print(printBanner())
 
-	// Initialize credentials
-	creds := auth.NewCredentials(oid, apiKey)
-
-	// Validate credentials
-	if err := creds.ValidateCredentials(); err != nil {
-		color.Red("Error: %v", err)
-		os.Exit(1)
-	}
+	// Initialize API clients, one per organization
+	orgs, exitStatus := connectOrgs(cmd)
 
 	if len(addTags) == 0 && len(removeTags) == 0 {
 		color.Red("Error: at least one of --add-tags or --remove-tags must be specified")
@@ -784,32 +1149,22 @@ func runTagMultiple(cmd *cobra.Command, args []string) {
 		WithTags: true, // We need tags for proper filtering
 	}
 
-	// List sensors
+	// List sensors and filter them based on hostname and platform
 	color.Blue("Retrieving sensors...")
-	sensors, err := api.ListSensors(creds, opts)
-	if err != nil {
-		color.Red("Failed to retrieve sensors: %v", err)
-		os.Exit(1)
-	}
-
-	// Filter sensors based on hostname and platform
-	filtered := filterSensors(sensors, nil)
+	filtered, failedOrgs := selectSensors(ctx, orgs, opts, sensorMatchesFilters)
 
 	if len(filtered) == 0 {
 		color.Yellow("No sensors match the specified filters")
-		os.Exit(0)
+		exitWithOrgSummary(ctx, orgs, nil, nil, failedOrgs, exitStatus)
 	}
 
 	// Confirm with user
 	color.Yellow("\nFound %d sensors matching filters:", len(filtered))
-	for _, sensor := range filtered {
-		fmt.Printf("- %s (%s) [%s]\n", sensor.Hostname, sensor.SID, sensor.GetPlatformString())
+	for _, ts := range filtered {
+		fmt.Printf("- %s%s (%s) [%s]\n", ts.org.prefix(), ts.sensor.Hostname, ts.sensor.SID, ts.sensor.GetPlatformString())
 	}
 
-	fmt.Print("\nDo you want to proceed with tagging these sensors? [y/N] ")
-	var response string
-	fmt.Scanln(&response)
-	if strings.ToLower(response) != "y" {
+	if !confirm(ctx, "\nDo you want to proceed with tagging these sensors? [y/N] ") {
 		color.Yellow("Operation cancelled")
 		os.Exit(0)
 	}
@@ -817,139 +1172,113 @@ func runTagMultiple(cmd *cobra.Command, args []string) {
 	// Tag multiple sensors
 	// Tag each sensor
 	color.Blue("\nUpdating sensor tags...")
-	for _, sensor := range filtered {
-		if err := api.TagSensor(creds, sensor.SID, api.TagSensorRequest{
+	var taggedCount, failCount atomic.Int64
+	results := runOnSensors(ctx, filtered, func(ctx context.Context, org *orgClient, sensor api.Sensor) error {
+		if err := org.client.TagSensor(ctx, sensor.SID, api.TagSensorRequest{
 			AddTags:    addTags,
 			RemoveTags: removeTags,
 		}); err != nil {
-			color.Red("Failed to tag sensor %s: %v", sensor.SID, err)
-			os.Exit(1)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			printLocked(func() {
+				color.Red("%sFailed to tag sensor %s: %v", org.prefix(), sensor.SID, err)
+			})
+			failCount.Add(1)
+			return err
 		}
-		color.Green("Successfully tagged sensor %s", sensor.SID)
-	}
+		printLocked(func() {
+			color.Green("%sSuccessfully tagged sensor %s", org.prefix(), sensor.SID)
+		})
+		taggedCount.Add(1)
+		return nil
+	})
+	skippedCount := countSkipped(ctx, results)
 
 	// Print summary
+	printFailedSensors(ctx, filtered, results)
 	fmt.Println()
 	if len(addTags) > 0 {
-		color.Green("Successfully tagged %d sensors with added tags: %v", len(filtered), addTags)
+		color.Green("Successfully tagged %d sensors with added tags: %v", taggedCount.Load(), addTags)
 	}
 	if len(removeTags) > 0 {
-		color.Green("Successfully tagged %d sensors with removed tags: %v", len(filtered), removeTags)
+		color.Green("Successfully tagged %d sensors with removed tags: %v", taggedCount.Load(), removeTags)
 	}
-}
-
-func outputResults(sensors []api.Sensor) {
-	switch output {
-	case "json":
-		outputJSON(sensors)
-	case "csv":
-		outputCSV(sensors)
-	default:
-		outputText(sensors)
+	if failCount.Load() > 0 {
+		color.Red("Failed to tag %d sensors", failCount.Load())
 	}
+	printRetrySummary(orgsClients(orgs)...)
+	printCancelled(ctx, skippedCount, "sensors")
+	exitWithOrgSummary(ctx, orgs, filtered, results, failedOrgs, combineExitCodes(exitStatus, resultsExitCode(ctx, results)))
 }
 
-func filterSensors(sensors []api.Sensor, onlineStatuses *api.OnlineStatusResponse) []api.Sensor {
-	var filtered []api.Sensor
-
-	for _, sensor := range sensors {
-		include := true
-
-		// Filter by hostname if specified
-		if filterHostname != "" {
-			pattern := strings.ReplaceAll(filterHostname, "*", ".*")
-			matched, err := regexp.MatchString(pattern, sensor.Hostname)
-			if err != nil || !matched {
-				include = false
-				continue
-			}
-		}
-
-		// Filter by platform if specified
-		if filterPlatform != "" {
-			platformStr := strings.ToLower(sensor.GetPlatformString())
-			if !strings.EqualFold(platformStr, filterPlatform) {
-				include = false
-				continue
-			}
-		}
-
-		// Filter by tag if specified
-		if filterTag != "" {
-			pattern := strings.ReplaceAll(filterTag, "*", ".*")
-			tagFound := false
-			for _, tag := range sensor.Tags {
-				matched, err := regexp.MatchString(pattern, tag)
-				if err == nil && matched {
-					tagFound = true
-					break
-				}
-			}
-			if !tagFound {
-				include = false
-				continue
-			}
-		}
+// sensorMatchesFilters reports whether a sensor passes the SID lists,
+// the hostname, platform, tag, address, online, seal and time filter
+// flags and --select.
+func sensorMatchesFilters(sensor api.Sensor) bool {
+	// Keep only listed sensors if a SID list is given; checked first so
+	// that listed sensors excluded by other filters still count as found
+	if sensorRefs != nil && !sensorRefs.Match(sensor) {
+		return false
+	}
 
-		// Filter by online status if specified
-		if onlineOnly && !sensor.IsOnline {
-			include = false
-			continue
-		}
+	// Filter by hostname if specified
+	if hostnamePattern != nil && !hostnamePattern.Match(sensor.Hostname) {
+		return false
+	}
 
-		if include {
-			filtered = append(filtered, sensor)
-		}
+	// Filter by platform if specified
+	if platformFilter != nil && !platformFilter[sensor.PlatformID] {
+		return false
 	}
 
-	return filtered
-}
+	// Filter by tag if specified
+	if tagPattern != nil && !tagPattern.MatchAny(sensor.Tags) {
+		return false
+	}
 
-func outputJSON(sensors []api.Sensor) {
-	jsonOutput, err := json.MarshalIndent(sensors, "", "  ")
-	if err != nil {
-		color.Red("Failed to format JSON: %v", err)
-		os.Exit(1)
+	// Filter by internal and external address if specified
+	if intAddrs != nil && !intAddrs.Contains(sensor.InternalIP) {
+		return false
+	}
+	if extAddrs != nil && !extAddrs.Contains(sensor.ExternalIP) {
+		return false
 	}
-	fmt.Println(string(jsonOutput))
-}
 
-func outputCSV(sensors []api.Sensor) {
-	// Print sensor details in CSV format
-	w := csv.NewWriter(os.Stdout)
-	w.Write([]string{"SID", "Hostname", "Platform", "Architecture", "Last Seen", "Enrollment Time", "External IP", "Internal IP", "Online", "Tags"})
+	// Filter by online status if specified
+	if onlineOnly && !sensor.IsOnline {
+		return false
+	}
 
-	for _, sensor := range sensors {
-		// Format tags
-		tagsStr := strings.Join(sensor.Tags, ", ")
+	// Filter by seal state if specified
+	if (filterSealed && !sensor.IsSealed) || (filterUnsealed && sensor.IsSealed) {
+		return false
+	}
 
-		w.Write([]string{
-			sensor.SID,
-			sensor.Hostname,
-			sensor.GetPlatformString(),
-			sensor.GetArchitectureString(),
-			sensor.GetLastSeenString(),
-			sensor.GetEnrollmentTimeString(),
-			sensor.ExternalIP,
-			sensor.InternalIP,
-			fmt.Sprintf("%v", sensor.IsOnline),
-			tagsStr,
-		})
+	// Filter by --select expression if specified
+	if sensorSelector != nil && !sensorSelector.Match(sensor) {
+		return false
 	}
 
-	w.Flush()
+	return sensorMatchesTimeFilters(sensor)
 }
 
-func outputText(sensors []api.Sensor) {
+func outputText(sensors []targetSensor) {
 	color.Green("\nFound %d sensors:", len(sensors))
 	fmt.Println("\n" + color.New(color.FgHiBlack).Sprint("─────────────────────────────────────────"))
 
 	// Print sensor details in table format
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"SID", "Hostname", "Platform", "Status", "IP", "Last Seen", "Tags"})
+	header := []string{"SID", "Hostname", "Platform", "Status", "IP", "Last Seen", "Tags"}
+	if multiOrg() {
+		header = append([]string{"Org"}, header...)
+	}
+	table.SetHeader(header)
 	table.SetBorder(false)
+	table.SetAutoWrapText(false)
 
-	for _, sensor := range sensors {
+	for _, ts := range sensors {
+		sensor := ts.sensor
 		status := "OFFLINE"
 		if sensor.IsOnline {
 			status = "ONLINE"
@@ -958,15 +1287,19 @@ func outputText(sensors []api.Sensor) {
 		// Format tags
 		tagsStr := strings.Join(sensor.Tags, ", ")
 
-		table.Append([]string{
+		var row []string
+		if multiOrg() {
+			row = []string{ts.org.target.Label}
+		}
+		table.Append(append(row,
 			sensor.SID,
 			sensor.Hostname,
 			sensor.GetPlatformString(),
 			status,
 			sensor.ExternalIP,
-			sensor.GetLastSeenString(),
+			lastSeenText(sensor),
 			tagsStr,
-		})
+		))
 	}
 
 	table.Render()
@@ -978,12 +1311,24 @@ func outputSensorText(sensor api.Sensor) {
 	fmt.Printf("Hostname: %s\n", sensor.Hostname)
 	fmt.Printf("Platform: %s\n", sensor.GetPlatformString())
 	fmt.Printf("Architecture: %s\n", sensor.GetArchitectureString())
-	fmt.Printf("Last Seen: %s\n", sensor.GetLastSeenString())
-	fmt.Printf("Enrollment Time: %s\n", sensor.GetEnrollmentTimeString())
+	fmt.Printf("Last Seen: %s\n", lastSeenText(sensor))
+	fmt.Printf("Enrollment Time: %s\n", enrolledText(sensor))
 	fmt.Printf("External IP: %s\n", sensor.ExternalIP)
 	fmt.Printf("Internal IP: %s\n", sensor.InternalIP)
-	fmt.Printf("Version: %s\n", sensor.Version)
+	fmt.Printf("Version: %s\n", valueOrNone(sensor.Version))
+	fmt.Printf("Installer Version: %s\n", valueOrNone(sensor.InstallerVersion))
 	fmt.Printf("Online: %v\n", sensor.IsOnline)
+	fmt.Printf("Organization ID: %s\n", sensor.OID)
+	fmt.Printf("Installation ID: %s\n", valueOrNone(sensor.InstallationID))
+	fmt.Printf("MAC Address: %s\n", valueOrNone(sensor.MacAddr))
+	fmt.Printf("Isolated: %v (pending: %v)\n", sensor.IsIsolated, sensor.ShouldIsolate)
+	fmt.Printf("Sealed: %v (pending: %v)\n", sensor.IsSealed, sensor.ShouldSeal)
+	fmt.Printf("Kernel Available: %v\n", sensor.KernelAvailable)
+	if sensor.ExternalPlatform != 0 {
+		fmt.Printf("External Platform: %s\n", sensor.GetExternalPlatformString())
+	} else {
+		fmt.Printf("External Platform: None\n")
+	}
 
 	// Format and display tags
 	if len(sensor.Tags) > 0 {
@@ -993,43 +1338,31 @@ func outputSensorText(sensor api.Sensor) {
 	}
 }
 
+// valueOrNone returns s, or "None" if it is empty.
+func valueOrNone(s string) string {
+	if s == "" {
+		return "None"
+	}
+	return s
+}
+
 func runRunTask(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
 	// Print banner
 	fmt.Print(printBanner())
 
-	// Initialize credentials
-	creds := auth.NewCredentials(oid, apiKey)
-
-	// Validate credentials
-	if err := creds.ValidateCredentials(); err != nil {
-		color.Red("Error: %v", err)
-		os.Exit(1)
-	}
+	// Initialize API clients, one per organization
+	orgs, exitStatus := connectOrgs(cmd)
 
 	// List all sensors
 	color.Blue("Retrieving sensors...")
 	opts := &api.ListOptions{
-		WithTags:  true, // We need tags for filtering
-		FilterTag: filterTag,
+		WithTags: true, // We need tags for filtering
 	}
 
-	sensors, err := api.ListSensors(creds, opts)
-	if err != nil {
-		color.Red("Failed to retrieve sensors: %v", err)
-		os.Exit(1)
-	}
-
-	// Filter by platform if specified
-	var filtered []api.Sensor
-	if filterPlatform != "" {
-		for _, sensor := range sensors {
-			if strings.EqualFold(sensor.GetPlatformString(), filterPlatform) {
-				filtered = append(filtered, sensor)
-			}
-		}
-	} else {
-		filtered = sensors
-	}
+	// Filter by hostname, tag, platform, time and --select
+	filtered, failedOrgs := selectSensors(ctx, orgs, opts, sensorMatchesFilters)
 
 	if len(filtered) == 0 {
 		if filterTag != "" && filterPlatform != "" {
@@ -1041,23 +1374,21 @@ func runRunTask(cmd *cobra.Command, args []string) {
 		} else {
 			color.Yellow("No sensors match the specified filters")
 		}
-		os.Exit(0)
+		exitWithOrgSummary(ctx, orgs, nil, nil, failedOrgs, exitStatus)
 	}
 
 	// Confirm with user
 	color.Yellow("\nFound %d sensors matching filters:", len(filtered))
-	for _, sensor := range filtered {
+	for _, ts := range filtered {
+		sensor := ts.sensor
 		if len(sensor.Tags) > 0 {
-			fmt.Printf("- %s (%s) [Platform: %s, Tags: %v]\n", sensor.Hostname, sensor.SID, sensor.GetPlatformString(), sensor.Tags)
+			fmt.Printf("- %s%s (%s) [Platform: %s, Tags: %v]\n", ts.org.prefix(), sensor.Hostname, sensor.SID, sensor.GetPlatformString(), sensor.Tags)
 		} else {
-			fmt.Printf("- %s (%s) [Platform: %s]\n", sensor.Hostname, sensor.SID, sensor.GetPlatformString())
+			fmt.Printf("- %s%s (%s) [Platform: %s]\n", ts.org.prefix(), sensor.Hostname, sensor.SID, sensor.GetPlatformString())
 		}
 	}
 
-	fmt.Print("\nDo you want to proceed with running the command on these sensors? [y/N] ")
-	var response string
-	fmt.Scanln(&response)
-	if strings.ToLower(response) != "y" {
+	if !confirm(ctx, "\nDo you want to proceed with running the command on these sensors? [y/N] ") {
 		color.Yellow("Operation cancelled")
 		os.Exit(0)
 	}
@@ -1077,88 +1408,104 @@ func runRunTask(cmd *cobra.Command, args []string) {
 
 	// Run commands on each sensor
 	color.Blue("\nExecuting commands on sensors...")
-	var successCount, failCount int
-	for _, sensor := range filtered {
+	var successCount, failCount atomic.Int64
+	results := runOnSensors(ctx, filtered, func(ctx context.Context, org *orgClient, sensor api.Sensor) error {
+		var sensorErr error
 		for i, command := range commands {
 			if i > 0 && taskRandomDelay {
-				addRandomDelay()
+				addRandomDelay(ctx)
 			}
 
-			// Debug output
-			color.Blue("Sending task to sensor: %s (%s)", sensor.Hostname, sensor.SID)
-			color.Blue("Command: %s", command)
-			color.Blue("Context: %s", taskContext)
+			slog.Debug("sending task to sensor",
+				slog.String("oid", org.target.OID),
+				slog.String("hostname", sensor.Hostname),
+				slog.String("sid", sensor.SID),
+				slog.String("command", command),
+				slog.String("context", taskContext),
+			)
 
 			if taskReliable {
 				// Use reliable tasking
-				if err := api.CreateReliableTask(creds, sensor.SID, command, taskContext, taskTTL); err != nil {
-					color.Red("Failed to send reliable task to sensor %s (%s): %v", sensor.Hostname, sensor.SID, err)
-					failCount++
+				if err := org.client.CreateReliableTask(ctx, sensor.SID, command, taskContext, taskTTL); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					printLocked(func() {
+						color.Red("%sFailed to send reliable task to sensor %s (%s): %v", org.prefix(), sensor.Hostname, sensor.SID, err)
+					})
+					failCount.Add(1)
+					sensorErr = err
 				} else {
-					color.Green("Successfully queued reliable task for sensor %s (%s): %s", sensor.Hostname, sensor.SID, command)
-					successCount++
+					printLocked(func() {
+						color.Green("%sSuccessfully queued reliable task for sensor %s (%s): %s", org.prefix(), sensor.Hostname, sensor.SID, command)
+					})
+					successCount.Add(1)
 				}
 			} else {
 				// Use regular tasking
-				if _, err := api.RunCommand(creds, sensor.SID, command, taskInvestigationID); err != nil {
-					color.Red("Failed to run command on sensor %s (%s): %v", sensor.Hostname, sensor.SID, err)
-					failCount++
+				if _, err := org.client.RunCommand(ctx, sensor.SID, command, taskInvestigationID); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					printLocked(func() {
+						color.Red("%sFailed to run command on sensor %s (%s): %v", org.prefix(), sensor.Hostname, sensor.SID, err)
+					})
+					failCount.Add(1)
+					sensorErr = err
 				} else {
-					color.Green("Successfully sent command to sensor %s (%s): %s", sensor.Hostname, sensor.SID, command)
-					successCount++
+					printLocked(func() {
+						color.Green("%sSuccessfully sent command to sensor %s (%s): %s", org.prefix(), sensor.Hostname, sensor.SID, command)
+					})
+					successCount.Add(1)
 				}
 			}
 		}
-	}
+		return sensorErr
+	})
+	skippedCount := countSkipped(ctx, results)
 
 	// Print summary
+	printFailedSensors(ctx, filtered, results)
 	fmt.Println()
-	if successCount > 0 {
+	if successCount.Load() > 0 {
 		if taskReliable {
-			color.Green("Successfully queued reliable command for %d sensors", successCount)
+			color.Green("Successfully queued reliable command for %d sensors", successCount.Load())
 		} else {
-			color.Green("Successfully sent command to %d sensors", successCount)
+			color.Green("Successfully sent command to %d sensors", successCount.Load())
 		}
 	}
-	if failCount > 0 {
+	if failCount.Load() > 0 {
 		if taskReliable {
-			color.Red("Failed to queue reliable command for %d sensors", failCount)
+			color.Red("Failed to queue reliable command for %d sensors", failCount.Load())
 		} else {
-			color.Red("Failed to send command to %d sensors", failCount)
+			color.Red("Failed to send command to %d sensors", failCount.Load())
 		}
 	}
-	if successCount > 0 {
+	if successCount.Load() > 0 {
 		color.Yellow("\nNote: Command output is not available through the API. Check the LimaCharlie web interface for results.")
 	}
+	printRetrySummary(orgsClients(orgs)...)
+	printCancelled(ctx, skippedCount, "sensors")
+	exitWithOrgSummary(ctx, orgs, filtered, results, failedOrgs, combineExitCodes(exitStatus, resultsExitCode(ctx, results)))
 }
 
 func runPutTask(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
 	// Print banner
 	fmt.Print(printBanner())
 
-	// Initialize credentials
-	creds := auth.NewCredentials(oid, apiKey)
-
-	// Validate credentials
-	if err := creds.ValidateCredentials(); err != nil {
-		color.Red("Error: %v", err)
-		os.Exit(1)
-	}
+	// Initialize API clients, one per organization
+	orgs, exitStatus := connectOrgs(cmd)
 
 	// List all sensors
 	color.Blue("Retrieving sensors...")
 	opts := &api.ListOptions{
-		WithTags: filterTag != "", // Only fetch tags if filtering by tag
-	}
-
-	sensors, err := api.ListSensors(creds, opts)
-	if err != nil {
-		color.Red("Failed to retrieve sensors: %v", err)
-		os.Exit(1)
+		WithTags: needTags(), // Only fetch tags if filtering by tag
 	}
 
 	// Filter sensors based on hostname and/or tag
-	filtered := filterSensors(sensors, nil)
+	filtered, failedOrgs := selectSensors(ctx, orgs, opts, sensorMatchesFilters)
 
 	if len(filtered) == 0 {
 		if filterHostname != "" {
@@ -1167,7 +1514,10 @@ func runPutTask(cmd *cobra.Command, args []string) {
 		if filterTag != "" {
 			color.Yellow("No sensors match the tag filter: %s", filterTag)
 		}
-		os.Exit(0)
+		if filterHostname == "" && filterTag == "" {
+			color.Yellow("No sensors match the specified filters")
+		}
+		exitWithOrgSummary(ctx, orgs, nil, nil, failedOrgs, exitStatus)
 	}
 
 	// Confirm with user
@@ -1175,22 +1525,22 @@ func runPutTask(cmd *cobra.Command, args []string) {
 		color.Yellow("\nFound %d sensors matching hostname filter '%s' and tag filter '%s':", len(filtered), filterHostname, filterTag)
 	} else if filterHostname != "" {
 		color.Yellow("\nFound %d sensors matching hostname filter '%s':", len(filtered), filterHostname)
-	} else {
+	} else if filterTag != "" {
 		color.Yellow("\nFound %d sensors matching tag filter '%s':", len(filtered), filterTag)
+	} else {
+		color.Yellow("\nFound %d sensors matching filters:", len(filtered))
 	}
 
-	for _, sensor := range filtered {
+	for _, ts := range filtered {
+		sensor := ts.sensor
 		if len(sensor.Tags) > 0 {
-			fmt.Printf("- %s (%s) [Tags: %v]\n", sensor.Hostname, sensor.SID, sensor.Tags)
+			fmt.Printf("- %s%s (%s) [Tags: %v]\n", ts.org.prefix(), sensor.Hostname, sensor.SID, sensor.Tags)
 		} else {
-			fmt.Printf("- %s (%s)\n", sensor.Hostname, sensor.SID)
+			fmt.Printf("- %s%s (%s)\n", ts.org.prefix(), sensor.Hostname, sensor.SID)
 		}
 	}
 
-	fmt.Print("\nDo you want to proceed with uploading the file to these sensors? [y/N] ")
-	var response string
-	fmt.Scanln(&response)
-	if strings.ToLower(response) != "y" {
+	if !confirm(ctx, "\nDo you want to proceed with uploading the file to these sensors? [y/N] ") {
 		color.Yellow("Operation cancelled")
 		os.Exit(0)
 	}
@@ -1211,54 +1561,77 @@ func runPutTask(cmd *cobra.Command, args []string) {
 
 	// Run commands on each sensor
 	color.Blue("\nUploading files to sensors...")
-	var successCount, failCount int
-	for _, sensor := range filtered {
+	var successCount, failCount atomic.Int64
+	results := runOnSensors(ctx, filtered, func(ctx context.Context, org *orgClient, sensor api.Sensor) error {
+		var sensorErr error
 		for i, command := range commands {
 			if i > 0 {
-				addRandomDelay()
+				addRandomDelay(ctx)
 			}
 
 			if taskReliable {
 				// Use reliable tasking
-				if err := api.CreateReliableTask(creds, sensor.SID, command, taskContext, taskTTL); err != nil {
-					color.Red("Failed to send reliable task to sensor %s (%s): %v", sensor.Hostname, sensor.SID, err)
-					failCount++
+				if err := org.client.CreateReliableTask(ctx, sensor.SID, command, taskContext, taskTTL); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					printLocked(func() {
+						color.Red("%sFailed to send reliable task to sensor %s (%s): %v", org.prefix(), sensor.Hostname, sensor.SID, err)
+					})
+					failCount.Add(1)
+					sensorErr = err
 				} else {
-					color.Green("Successfully queued reliable task for sensor %s (%s): %s", sensor.Hostname, sensor.SID, command)
-					successCount++
+					printLocked(func() {
+						color.Green("%sSuccessfully queued reliable task for sensor %s (%s): %s", org.prefix(), sensor.Hostname, sensor.SID, command)
+					})
+					successCount.Add(1)
 				}
 			} else {
 				// Use regular tasking
-				if _, err := api.TaskSensor(creds, sensor.SID, []string{command}, taskInvestigationID); err != nil {
-					color.Red("Failed to upload file to sensor %s (%s): %v", sensor.Hostname, sensor.SID, err)
-					failCount++
+				if _, err := org.client.TaskSensor(ctx, sensor.SID, []string{command}, taskInvestigationID); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					printLocked(func() {
+						color.Red("%sFailed to upload file to sensor %s (%s): %v", org.prefix(), sensor.Hostname, sensor.SID, err)
+					})
+					failCount.Add(1)
+					sensorErr = err
 				} else {
-					color.Green("Successfully sent upload command to sensor %s (%s): %s", sensor.Hostname, sensor.SID, command)
-					successCount++
+					printLocked(func() {
+						color.Green("%sSuccessfully sent upload command to sensor %s (%s): %s", org.prefix(), sensor.Hostname, sensor.SID, command)
+					})
+					successCount.Add(1)
 				}
 			}
 		}
-	}
+		return sensorErr
+	})
+	skippedCount := countSkipped(ctx, results)
 
 	// Print summary
+	printFailedSensors(ctx, filtered, results)
 	fmt.Println()
-	if successCount > 0 {
+	if successCount.Load() > 0 {
 		if taskReliable {
-			color.Green("Successfully queued reliable upload task for %d sensors", successCount)
+			color.Green("Successfully queued reliable upload task for %d sensors", successCount.Load())
 		} else {
-			color.Green("Successfully sent upload command to %d sensors", successCount)
+			color.Green("Successfully sent upload command to %d sensors", successCount.Load())
 		}
 	}
-	if failCount > 0 {
+	if failCount.Load() > 0 {
 		if taskReliable {
-			color.Red("Failed to queue reliable upload task for %d sensors", failCount)
+			color.Red("Failed to queue reliable upload task for %d sensors", failCount.Load())
 		} else {
-			color.Red("Failed to send upload command to %d sensors", failCount)
+			color.Red("Failed to send upload command to %d sensors", failCount.Load())
 		}
 	}
-	if successCount > 0 {
+	if successCount.Load() > 0 {
 		color.Yellow("\nNote: Upload status is not available through the API. Check the LimaCharlie web interface for results.")
 	}
+	printRetrySummary(orgsClients(orgs)...)
+	printCancelled(ctx, skippedCount, "sensors")
+	exitWithOrgSummary(ctx, orgs, filtered, results, failedOrgs, combineExitCodes(exitStatus, resultsExitCode(ctx, results)))
 }
 
 // Add helper function to read commands from file
@@ -1284,11 +1657,88 @@ func readCommandsFromFile(filePath string) ([]string, error) {
 	return commands, nil
 }
 
-// Add random delay function
-func addRandomDelay() {
+// Add random delay function. The wait ends early if ctx is cancelled.
+func addRandomDelay(ctx context.Context) {
 	if taskRandomDelay {
 		delay := time.Duration(5+rand.Intn(11)) * time.Second // Random delay between 5-15 seconds
-		color.Yellow("Waiting %v before next command...", delay)
-		time.Sleep(delay)
+		printLocked(func() {
+			color.Yellow("Waiting %v before next command...", delay)
+		})
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// confirm prints the prompt and waits for a yes/no answer on stdin.
+// It returns false if the answer is not "y" or if ctx is cancelled
+// while waiting.
+func confirm(ctx context.Context, prompt string) bool {
+	fmt.Print(prompt)
+
+	// Stdin may already have been read as a SID list
+	input := os.Stdin
+	if stdinUsed {
+		tty, err := terminalInput()
+		if err != nil {
+			fmt.Println()
+			color.Red("Error: %v", err)
+			return false
+		}
+		defer tty.Close()
+		input = tty
+	}
+
+	answer := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(input).ReadString('\n')
+		answer <- strings.TrimSpace(line)
+	}()
+
+	select {
+	case response := <-answer:
+		return strings.ToLower(response) == "y"
+	case <-ctx.Done():
+		fmt.Println()
+		return false
+	}
+}
+
+// printRetrySummary reports how many API requests were retried, if any.
+func printRetrySummary(clients ...*api.Client) {
+	var retries int64
+	for _, client := range clients {
+		retries += client.Retries()
+	}
+	if retries > 0 {
+		color.Yellow("Retried %d API requests after rate limiting or server errors", retries)
+	}
+}
+
+// printCancelled reports that a command stopped early because of an
+// interrupt or the --timeout deadline. remaining is the number of items
+// that were not processed; it is omitted when zero.
+func printCancelled(ctx context.Context, remaining int, what string) {
+	if ctx.Err() == nil {
+		return
+	}
+
+	reason := "Interrupted"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = fmt.Sprintf("Timed out after %v", timeout)
+	}
+	if remaining > 0 {
+		color.Red("%s: %d %s were not processed", reason, remaining, what)
+	} else {
+		color.Red("%s before the operation completed", reason)
+	}
+}
+
+// exitIfCancelled terminates the process with the interrupt or timeout
+// exit code if ctx was cancelled.
+func exitIfCancelled(ctx context.Context) {
+	if ctx.Err() != nil {
+		os.Exit(exitCode(ctx.Err()))
 	}
 }
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/config"
+	"LC_utils/internal/fanout"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Multi-organization flags shared by the sensor commands
+	targetOrgs     []string
+	allProfiles    bool
+	orgConcurrency int
+)
+
+// addOrgFlags registers the --orgs, --all-profiles and --org-concurrency
+// flags on a sensor command.
+func addOrgFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&targetOrgs, "orgs", nil, "Run against these organizations in parallel (comma-separated profile names or OIDs)")
+	cmd.Flags().BoolVar(&allProfiles, "all-profiles", false, "Run against the organization of every configured profile")
+	cmd.Flags().IntVar(&orgConcurrency, "org-concurrency", 4, "Number of organizations processed in parallel with --orgs/--all-profiles")
+}
+
+// multiOrg reports whether the command targets several organizations.
+func multiOrg() bool {
+	return len(targetOrgs) > 0 || allProfiles
+}
+
+// orgTarget is the connection settings of one organization.
+type orgTarget struct {
+	// Label identifies the organization in output: the profile name, or
+	// the OID when it was given directly
+	Label string
+	// Profile is the profile name, empty for a bare OID
+	Profile string
+	OID     string
+	// UID is set when APIKey is a user API key
+	UID    string
+	APIKey string
+	// JWT is a pre-issued token used instead of APIKey
+	JWT         string
+	Endpoint    string
+	JWTEndpoint string
+}
+
+// currentTarget returns the organization of a single-org run, resolved
+// from the global flags, environment and profile.
+func currentTarget() orgTarget {
+	label := activeProfile
+	if label == "" {
+		label = oid
+	}
+	return orgTarget{
+		Label:       label,
+		Profile:     activeProfile,
+		OID:         oid,
+		UID:         userID,
+		APIKey:      apiKey,
+		JWT:         userJWT,
+		Endpoint:    apiEndpoint,
+		JWTEndpoint: jwtEndpoint,
+	}
+}
+
+// orgClient is an organization together with its API client.
+type orgClient struct {
+	target orgTarget
+	client *api.Client
+}
+
+// prefix returns "[label] " to mark output lines in multi-org runs, and
+// "" otherwise so that single-org output is unchanged.
+func (o *orgClient) prefix() string {
+	if !multiOrg() {
+		return ""
+	}
+	return "[" + o.target.Label + "] "
+}
+
+// targetSensor is a selected sensor and the organization it belongs to.
+type targetSensor struct {
+	org    *orgClient
+	sensor api.Sensor
+}
+
+// resolveTargets returns the organizations named by --orgs or
+// --all-profiles. Entries of --orgs that match a profile use that
+// profile; any other entry is an OID used with the global credentials,
+// which suits user API keys and user JWTs spanning many organizations.
+// Command-line flags override profile values, and profile fields left
+// empty fall back to the global settings.
+func resolveTargets(cmd *cobra.Command) ([]orgTarget, error) {
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return nil, err
+	}
+
+	names := targetOrgs
+	if allProfiles {
+		if len(targetOrgs) > 0 {
+			return nil, fmt.Errorf("cannot use both --orgs and --all-profiles")
+		}
+		names = cfg.Names()
+		if len(names) == 0 {
+			return nil, fmt.Errorf("--all-profiles: no profiles configured")
+		}
+	}
+
+	var targets []orgTarget
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		t := orgTarget{Label: name, OID: name, JWT: userJWT}
+		if p, ok := cfg.Profiles[name]; ok {
+			t = orgTarget{Label: name, Profile: name, OID: p.OID, UID: p.UID, Endpoint: p.Endpoint, JWTEndpoint: p.JWTEndpoint}
+			if !apiKeyFlagged(cmd) {
+				if t.APIKey, err = p.ResolveAPIKey(cmd.Context()); err != nil {
+					return nil, fmt.Errorf("profile %q: %w", name, err)
+				}
+			}
+		}
+
+		override := func(flag string, value *string, global string) {
+			if flagChanged(cmd, flag) || *value == "" {
+				*value = global
+			}
+		}
+		// The user ID belongs with the key it came with
+		if t.APIKey == "" || apiKeyFlagged(cmd) || flagChanged(cmd, "uid") {
+			t.UID = userID
+		}
+		if t.APIKey == "" || apiKeyFlagged(cmd) {
+			t.APIKey = apiKey
+		}
+		override("endpoint", &t.Endpoint, apiEndpoint)
+		override("jwt-endpoint", &t.JWTEndpoint, jwtEndpoint)
+
+		if t.OID == "" {
+			return nil, fmt.Errorf("profile %q has no organization ID", name)
+		}
+		if t.APIKey == "" && t.JWT == "" {
+			return nil, fmt.Errorf("no API key for organization %q (set one in its profile, --api-key, LC_API_KEY or LC_JWT)", name)
+		}
+		targets = append(targets, t)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("--orgs: no organizations given")
+	}
+	return targets, nil
+}
+
+// connectOrgs creates and validates an API client for every target
+// organization in parallel. Organizations that fail are reported and
+// left out; it is fatal if none succeeds. Without --orgs or
+// --all-profiles it connects to the single current organization, and
+// any failure is fatal.
+//
+// Parameters:
+//   - cmd: The running command, used to resolve the targets
+//
+// Returns:
+//   - []*orgClient: The connected organizations, in target order
+//   - int: Exit code summarizing the failures, exitOK if there were none
+func connectOrgs(cmd *cobra.Command) ([]*orgClient, int) {
+	ctx := cmd.Context()
+	if !multiOrg() {
+		client, err := newAPIClient(ctx)
+		if err != nil {
+			fatal("Error", err)
+		}
+		return []*orgClient{{target: currentTarget(), client: client}}, exitOK
+	}
+
+	targets, err := resolveTargets(cmd)
+	if err != nil {
+		fatal("Error", err)
+	}
+
+	orgs := make([]*orgClient, len(targets))
+	pool := fanout.New(orgConcurrency, 0)
+	results := pool.Run(ctx, len(targets), func(ctx context.Context, i int) error {
+		client, err := newAPIClientFor(ctx, targets[i])
+		if err != nil {
+			return err
+		}
+		orgs[i] = &orgClient{target: targets[i], client: client}
+		return nil
+	}, nil)
+	exitIfCancelled(ctx)
+
+	var connected []*orgClient
+	for i, r := range results {
+		if r.Err != nil {
+			fmt.Fprintln(os.Stderr, color.RedString("[%s] Failed to connect: %v", targets[i].Label, r.Err))
+			continue
+		}
+		connected = append(connected, orgs[i])
+	}
+	code := resultsExitCode(ctx, results)
+	if len(connected) == 0 {
+		fmt.Fprintln(os.Stderr, color.RedString("Could not connect to any organization"))
+		os.Exit(code)
+	}
+	return connected, code
+}
+
+// selectSensors lists the sensors of every organization in parallel and
+// keeps those accepted by match. In a single-org run a listing error is
+// fatal; with several organizations, the failing ones are reported and
+// skipped.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation
+//   - orgs: The organizations to list
+//   - opts: Listing options passed to the API
+//   - match: Client-side filter applied to every sensor
+//
+// Returns:
+//   - []targetSensor: The selected sensors, grouped by organization
+//   - map[*orgClient]error: Listing errors of the organizations that failed
+func selectSensors(ctx context.Context, orgs []*orgClient, opts *api.ListOptions, match func(api.Sensor) bool) ([]targetSensor, map[*orgClient]error) {
+	perOrg := make([][]targetSensor, len(orgs))
+	pool := fanout.New(orgConcurrency, 0)
+	results := pool.Run(ctx, len(orgs), func(ctx context.Context, i int) error {
+		org := orgs[i]
+		return org.client.ForEachSensor(ctx, opts, func(sensor api.Sensor) error {
+			if match(sensor) {
+				perOrg[i] = append(perOrg[i], targetSensor{org: org, sensor: sensor})
+			}
+			return nil
+		})
+	}, nil)
+
+	failed := map[*orgClient]error{}
+	var selected []targetSensor
+	for i, r := range results {
+		if r.Err != nil {
+			if !multiOrg() {
+				if ctx.Err() != nil {
+					printCancelled(ctx, 0, "")
+					exitIfCancelled(ctx)
+				}
+				fatal("Failed to retrieve sensors", r.Err)
+			}
+			color.Red("%sFailed to retrieve sensors: %v", orgs[i].prefix(), r.Err)
+			failed[orgs[i]] = r.Err
+			continue
+		}
+		selected = append(selected, perOrg[i]...)
+	}
+	exitIfCancelled(ctx)
+	warnUnmatchedRefs()
+	return selected, failed
+}
+
+// orgCounts is one row of the per-organization summary.
+type orgCounts struct {
+	selected, succeeded, failed, skipped int
+	// err is the error that stopped the organization, if any
+	err error
+}
+
+// tallyOrgResults counts, per organization, the sensors a bulk command
+// selected and how many of them succeeded, failed or were skipped.
+// Organizations whose listing failed carry that error.
+func tallyOrgResults(ctx context.Context, orgs []*orgClient, selection []targetSensor, results []fanout.Result, failed map[*orgClient]error) []orgCounts {
+	index := map[*orgClient]int{}
+	counts := make([]orgCounts, len(orgs))
+	for i, org := range orgs {
+		index[org] = i
+		counts[i].err = failed[org]
+	}
+	for i, ts := range selection {
+		c := &counts[index[ts.org]]
+		c.selected++
+		if results == nil {
+			continue
+		}
+		switch r := results[i]; {
+		case !r.Started || (ctx.Err() != nil && errors.Is(r.Err, ctx.Err())):
+			c.skipped++
+		case r.Err != nil:
+			c.failed++
+		default:
+			c.succeeded++
+		}
+	}
+	return counts
+}
+
+// printOrgSummary writes a table with one row per organization. With
+// outcomes set, it shows how many sensors succeeded, failed or were
+// skipped; otherwise only the number of sensors found.
+func printOrgSummary(w io.Writer, orgs []*orgClient, counts []orgCounts, outcomes bool) {
+	fmt.Fprintln(w)
+	table := tablewriter.NewWriter(w)
+	if outcomes {
+		table.SetHeader([]string{"Org", "OID", "Selected", "Succeeded", "Failed", "Skipped", "Status"})
+	} else {
+		table.SetHeader([]string{"Org", "OID", "Sensors", "Status"})
+	}
+	table.SetBorder(false)
+
+	for i, org := range orgs {
+		c := counts[i]
+		status := "OK"
+		switch {
+		case c.err != nil:
+			status = c.err.Error()
+		case c.failed > 0:
+			status = "FAILED"
+		case c.skipped > 0:
+			status = "INCOMPLETE"
+		}
+		if outcomes {
+			table.Append([]string{org.target.Label, org.target.OID, fmt.Sprint(c.selected), fmt.Sprint(c.succeeded), fmt.Sprint(c.failed), fmt.Sprint(c.skipped), status})
+		} else {
+			table.Append([]string{org.target.Label, org.target.OID, fmt.Sprint(c.selected), status})
+		}
+	}
+	table.Render()
+}
+
+// combineExitCodes merges exit codes: exitOK entries are ignored, a
+// single failure class is kept and mixed classes become exitError.
+func combineExitCodes(codes ...int) int {
+	code := exitOK
+	for _, c := range codes {
+		if c == exitOK {
+			continue
+		}
+		if code != exitOK && code != c {
+			return exitError
+		}
+		code = c
+	}
+	return code
+}
+
+// orgsClients returns the API client of every organization.
+func orgsClients(orgs []*orgClient) []*api.Client {
+	clients := make([]*api.Client, len(orgs))
+	for i, org := range orgs {
+		clients[i] = org.client
+	}
+	return clients
+}
+
+// exitWithOrgSummary ends a bulk command. In multi-org runs it first
+// prints the per-organization summary and folds organizations that
+// could not be listed into the exit code. It never returns.
+//
+// Parameters:
+//   - ctx: Context of the command
+//   - orgs: The connected organizations
+//   - selection: The selected sensors, nil if nothing was selected
+//   - results: Fan-out results for selection, nil if nothing ran
+//   - failed: Listing errors per organization
+//   - code: Exit code of the rest of the run
+func exitWithOrgSummary(ctx context.Context, orgs []*orgClient, selection []targetSensor, results []fanout.Result, failed map[*orgClient]error, code int) {
+	if multiOrg() {
+		printOrgSummary(os.Stdout, orgs, tallyOrgResults(ctx, orgs, selection, results, failed), results != nil)
+	}
+	exitIfCancelled(ctx)
+	os.Exit(failedOrgsExitCode(code, failed))
+}
+
+// failedOrgsExitCode returns the exit code for organizations whose
+// listing failed, combined with code from the rest of the run.
+func failedOrgsExitCode(code int, failed map[*orgClient]error) int {
+	for _, err := range failed {
+		code = combineExitCodes(code, exitCode(err))
+	}
+	return code
+}
+
+var (
+	// Orgs command flags
+	orgsOutput string
+	orgsQuiet  bool
+)
+
+// newOrgsCmd returns the `orgs` command, which lists the organizations
+// a user API key or user JWT can access.
+func newOrgsCmd() *cobra.Command {
+	orgsCmd := &cobra.Command{
+		Use:   "orgs",
+		Short: "List the organizations the user can access",
+		Long: `List the organizations a user API key (--uid with --api-key, or LC_UID with
+LC_API_KEY) or a user JWT in LC_JWT can access.
+
+The OIDs can be passed to --orgs to run a command across them.
+
+Example:
+  # Tag Windows sensors in every organization the user can access
+  lc-sensors tag-multiple --orgs "$(lc-sensors orgs -q)" --filter-platform windows --add-tags audited`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if userID == "" && userJWT == "" {
+				return fmt.Errorf("listing organizations requires a user API key (--uid or LC_UID) or a JWT in LC_JWT")
+			}
+			switch orgsOutput {
+			case "text", "json", "csv":
+			default:
+				return fmt.Errorf("--output must be one of: text, json, csv")
+			}
+			return promptAPIKeyIfMissing()
+		},
+		Run: runOrgs,
+	}
+	orgsCmd.Flags().StringVarP(&orgsOutput, "output", "f", "text", "Output format (text/json/csv)")
+	orgsCmd.Flags().BoolVarP(&orgsQuiet, "quiet", "q", false, "Print only the OIDs, comma-separated, for use with --orgs")
+	return orgsCmd
+}
+
+func runOrgs(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	// Scope a user key's token to all of the user's organizations
+	target := currentTarget()
+	if target.UID != "" {
+		target.OID = ""
+	}
+	client, err := newUserClient(ctx, target)
+	if err != nil {
+		fatal("Error", err)
+	}
+	orgs, err := client.UserOrgs(ctx)
+	if err != nil {
+		fatal("Failed to list organizations", err)
+	}
+
+	if orgsQuiet {
+		oids := make([]string, len(orgs))
+		for i, org := range orgs {
+			oids[i] = org.OID
+		}
+		fmt.Println(strings.Join(oids, ","))
+		return
+	}
+
+	switch orgsOutput {
+	case "json":
+		if orgs == nil {
+			orgs = []api.UserOrg{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(orgs); err != nil {
+			fatal("Failed to write output", err)
+		}
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		writer.Write([]string{"OID", "Name"})
+		for _, org := range orgs {
+			writer.Write([]string{org.OID, org.Name})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			fatal("Failed to write output", err)
+		}
+	default:
+		if len(orgs) == 0 {
+			color.Yellow("No accessible organizations found")
+			return
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "OID"})
+		table.SetBorder(false)
+		for _, org := range orgs {
+			table.Append([]string{org.Name, org.OID})
+		}
+		table.Render()
+		color.Blue("\nTotal organizations: %d", len(orgs))
+	}
+}
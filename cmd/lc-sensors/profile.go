@@ -0,0 +1,457 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"LC_utils/internal/auth"
+	"LC_utils/internal/config"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// profileName is the --profile flag
+	profileName string
+	// activeProfile is the name of the profile in effect, if any
+	activeProfile string
+
+	// Profile add flags
+	profileAPIKeyEnv   string
+	profileAPIKeyCmd   string
+	profileTheme       string
+	profileOutput      string
+	profileConcurrency int
+	profileForce       bool
+	profileUse         bool
+)
+
+// noProfileAnnotation marks commands, and their subcommands, that run
+// without resolving a profile.
+const noProfileAnnotation = "lc-sensors/no-profile"
+
+// needsProfile reports whether settings should be resolved from the
+// environment and config file before cmd runs.
+func needsProfile(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[noProfileAnnotation] != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// flagChanged reports whether the named flag exists on cmd and was set
+// on the command line.
+func flagChanged(cmd *cobra.Command, name string) bool {
+	f := cmd.Flags().Lookup(name)
+	return f != nil && f.Changed
+}
+
+// apiKeyFlagged reports whether the API key was given with --api-key or
+// --api-key-file.
+func apiKeyFlagged(cmd *cobra.Command) bool {
+	return flagChanged(cmd, "api-key") || flagChanged(cmd, "api-key-file")
+}
+
+// resolveSettings fills the global credential, endpoint and default
+// flags from the environment and the config file. Precedence, highest
+// first:
+//
+//  1. Command-line flags
+//  2. The profile selected with --profile or LC_PROFILE
+//  3. Environment variables (LC_ORG_ID, LC_API_KEY, LC_UID, LC_JWT, LC_API_ENDPOINT, LC_JWT_ENDPOINT)
+//  4. The config's current profile, set with `lc-sensors profile use`
+//  5. Built-in defaults
+//
+// The user ID always comes from the same place as the API key, and a
+// pre-issued JWT from LC_JWT replaces any API key of lower precedence.
+func resolveSettings(cmd *cobra.Command) error {
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return err
+	}
+
+	name := profileName
+	explicit := flagChanged(cmd, "profile")
+	if !explicit {
+		if env := os.Getenv("LC_PROFILE"); env != "" {
+			name = env
+			explicit = true
+		}
+	}
+	if name == "" {
+		name = cfg.CurrentProfile
+	}
+
+	profile := &config.Profile{}
+	if name != "" {
+		if profile, err = cfg.Profile(name); err != nil {
+			return err
+		}
+		activeProfile = name
+	}
+
+	// resolve sets target from the profile or the environment unless the
+	// flag was given. The profile value is only computed when it can win,
+	// so that a credential helper does not run needlessly. It reports
+	// whether the value came from the profile.
+	resolve := func(flag string, target *string, env string, fromProfile func() (string, error)) (bool, error) {
+		if flagChanged(cmd, flag) {
+			return false, nil
+		}
+		if !explicit && os.Getenv(env) != "" {
+			*target = os.Getenv(env)
+			return false, nil
+		}
+		value, err := fromProfile()
+		if err != nil {
+			return false, fmt.Errorf("profile %q: %w", name, err)
+		}
+		switch {
+		case value != "":
+			*target = value
+			return true, nil
+		case os.Getenv(env) != "":
+			*target = os.Getenv(env)
+		}
+		return false, nil
+	}
+	static := func(value string) func() (string, error) {
+		return func() (string, error) { return value, nil }
+	}
+
+	if _, err := resolve("oid", &oid, "LC_ORG_ID", static(profile.OID)); err != nil {
+		return err
+	}
+
+	// Credentials: --api-key or --api-key-file, then a pre-issued JWT
+	// from LC_JWT unless an explicit profile has a key, then the key
+	// from the profile or LC_API_KEY
+	if flagChanged(cmd, "api-key") && flagChanged(cmd, "api-key-file") {
+		return fmt.Errorf("cannot use both --api-key and --api-key-file")
+	}
+	keyFlagged := apiKeyFlagged(cmd)
+	switch {
+	case flagChanged(cmd, "api-key-file"):
+		if apiKey, err = auth.ReadAPIKeyFile(apiKeyFile); err != nil {
+			return err
+		}
+	case os.Getenv("LC_JWT") != "" && !(explicit && profile.HasAPIKey()):
+		userJWT = os.Getenv("LC_JWT")
+	}
+	if !keyFlagged && userJWT == "" {
+		keyFromProfile, err := resolve("api-key", &apiKey, "LC_API_KEY", func() (string, error) {
+			return profile.ResolveAPIKey(cmd.Context())
+		})
+		if err != nil {
+			return err
+		}
+		// The user ID belongs with the key it came with
+		if !flagChanged(cmd, "uid") {
+			if keyFromProfile {
+				userID = profile.UID
+			} else {
+				userID = os.Getenv("LC_UID")
+			}
+		}
+	} else if keyFlagged && !flagChanged(cmd, "uid") {
+		userID = os.Getenv("LC_UID")
+	}
+	if _, err := resolve("endpoint", &apiEndpoint, "LC_API_ENDPOINT", static(profile.Endpoint)); err != nil {
+		return err
+	}
+	if _, err := resolve("jwt-endpoint", &jwtEndpoint, "LC_JWT_ENDPOINT", static(profile.JWTEndpoint)); err != nil {
+		return err
+	}
+
+	// Defaults only apply when the flag was not given
+	if profile.Theme != "" && !cmd.Root().Flags().Changed("theme") {
+		theme = profile.Theme
+	}
+	if profile.Output != "" && !flagChanged(cmd, "output") {
+		output = profile.Output
+	}
+	if profile.Concurrency > 0 && !flagChanged(cmd, "concurrency") {
+		concurrency = profile.Concurrency
+	}
+
+	return nil
+}
+
+// requireCredentials checks that an organization ID and API key were
+// resolved from flags, the environment or a profile. When no API key was
+// found and standard input is a terminal, it asks for one.
+func requireCredentials() error {
+	// Each organization's credentials are checked in resolveTargets
+	if multiOrg() {
+		return nil
+	}
+	// A pre-issued JWT may name the organization itself
+	if oid == "" && userJWT == "" {
+		return fmt.Errorf("organization ID is required (set via --oid flag, LC_ORG_ID environment variable or a profile)")
+	}
+	return promptAPIKeyIfMissing()
+}
+
+// promptAPIKeyIfMissing asks for the API key on the terminal, without
+// echo, if none was resolved and there is no pre-issued JWT.
+func promptAPIKeyIfMissing() error {
+	if apiKey != "" || userJWT != "" {
+		return nil
+	}
+	if !auth.CanPromptAPIKey() {
+		return fmt.Errorf("API key is required (set via --api-key-file, --api-key flag, LC_API_KEY environment variable, LC_JWT or a profile)")
+	}
+	label := activeProfile
+	if label == "" {
+		label = oid
+	}
+	if label == "" {
+		label = userID
+	}
+	key, err := auth.PromptAPIKey(fmt.Sprintf("LimaCharlie API key for %s: ", label))
+	if err != nil {
+		return err
+	}
+	apiKey = key
+	return nil
+}
+
+// newProfileCmd returns the `profile` command and its subcommands.
+func newProfileCmd() *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage named profiles for organizations",
+		Long: `Manage named profiles stored in ` + config.DefaultPath() + `.
+
+A profile holds an organization ID, where to find its API key, optional
+endpoint overrides and defaults for theme, output format and concurrency.
+
+Settings are resolved in this order, highest first:
+  1. Command-line flags
+  2. The profile selected with --profile or LC_PROFILE
+  3. Environment variables (LC_ORG_ID, LC_API_KEY, LC_UID, LC_JWT, LC_API_ENDPOINT, LC_JWT_ENDPOINT)
+  4. The current profile, set with "lc-sensors profile use"
+  5. Built-in defaults`,
+		// Profile management works on the config file itself, so a
+		// broken or missing profile must not stop it
+		Annotations: map[string]string{noProfileAnnotation: "true"},
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add NAME",
+		Short: "Add a profile",
+		Long: `Add a profile from the --oid, --uid, --api-key, --api-key-file, --endpoint and
+--jwt-endpoint flags and the profile default flags below.
+
+Example:
+  # Store the org ID and read the key from $LC_PROD_KEY at run time
+  lc-sensors profile add prod --oid ORG_ID --api-key-env LC_PROD_KEY --concurrency 20
+
+  # A user API key, which can access every organization of the user
+  lc-sensors profile add me --uid USER_ID --api-key-env LC_USER_KEY
+
+  # Read the key from a password manager or a key file on each run
+  lc-sensors profile add lab --oid ORG_ID --api-key-command "pass show limacharlie/lab"
+  lc-sensors profile add ci --oid ORG_ID --api-key-file ~/.config/lc-sensors/ci.key`,
+		Args: cobra.ExactArgs(1),
+		RunE: runProfileAdd,
+	}
+	addCmd.Flags().StringVar(&profileAPIKeyEnv, "api-key-env", "", "Environment variable holding the API key (preferred over --api-key)")
+	addCmd.Flags().StringVar(&profileAPIKeyCmd, "api-key-command", "", "Shell command that prints the API key, e.g. \"pass show lc/prod\"")
+	addCmd.Flags().StringVar(&profileTheme, "theme", "", "Default visual theme (matrix, hacker, cyberpunk, retro)")
+	addCmd.Flags().StringVar(&profileOutput, "output", "", "Default list output format (text/json/csv)")
+	addCmd.Flags().IntVar(&profileConcurrency, "concurrency", 0, "Default number of sensors processed in parallel")
+	addCmd.Flags().BoolVar(&profileForce, "force", false, "Replace an existing profile with the same name")
+	addCmd.Flags().BoolVar(&profileUse, "use", false, "Make the new profile the current profile")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles",
+		Args:  cobra.NoArgs,
+		RunE:  runProfileList,
+	}
+
+	useCmd := &cobra.Command{
+		Use:   "use NAME",
+		Short: "Set the current profile",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runProfileUse,
+	}
+
+	removeCmd := &cobra.Command{
+		Use:     "remove NAME",
+		Aliases: []string{"rm"},
+		Short:   "Remove a profile",
+		Args:    cobra.ExactArgs(1),
+		RunE:    runProfileRemove,
+	}
+
+	profileCmd.AddCommand(addCmd, listCmd, useCmd, removeCmd)
+	return profileCmd
+}
+
+func runProfileAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := config.ValidateProfileName(name); err != nil {
+		return err
+	}
+	path := config.DefaultPath()
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := cfg.Profiles[name]; exists && !profileForce {
+		return fmt.Errorf("profile %q already exists (use --force to replace it)", name)
+	}
+	if oid == "" && userID == "" {
+		return fmt.Errorf("--oid is required unless --uid is given for a user API key")
+	}
+	sources := 0
+	for _, source := range []string{apiKey, profileAPIKeyEnv, apiKeyFile, profileAPIKeyCmd} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("use only one of --api-key, --api-key-env, --api-key-file and --api-key-command")
+	}
+	keyFile := apiKeyFile
+	if keyFile != "" {
+		// Check the file now rather than on first use
+		if _, err := auth.ReadAPIKeyFile(keyFile); err != nil {
+			return err
+		}
+		if keyFile, err = filepath.Abs(keyFile); err != nil {
+			return err
+		}
+	}
+	if profileTheme != "" {
+		if _, ok := themes[profileTheme]; !ok {
+			return fmt.Errorf("--theme must be one of: matrix, hacker, cyberpunk, retro")
+		}
+	}
+	switch profileOutput {
+	case "", "text", "json", "csv":
+	default:
+		return fmt.Errorf("--output must be one of: text, json, csv")
+	}
+	if profileConcurrency < 0 {
+		return fmt.Errorf("--concurrency must be positive")
+	}
+
+	cfg.Profiles[name] = &config.Profile{
+		OID:           oid,
+		UID:           userID,
+		APIKey:        apiKey,
+		APIKeyEnv:     profileAPIKeyEnv,
+		APIKeyFile:    keyFile,
+		APIKeyCommand: profileAPIKeyCmd,
+		Endpoint:      apiEndpoint,
+		JWTEndpoint:   jwtEndpoint,
+		Theme:         profileTheme,
+		Output:        profileOutput,
+		Concurrency:   profileConcurrency,
+	}
+	if profileUse || cfg.CurrentProfile == "" {
+		cfg.CurrentProfile = name
+	}
+
+	if err := cfg.Save(path); err != nil {
+		return err
+	}
+
+	color.Green("Saved profile %q to %s", name, path)
+	if apiKey != "" {
+		color.Yellow("The API key is stored in the config file; consider --api-key-env, --api-key-file or --api-key-command instead.")
+	}
+	if sources == 0 {
+		color.Yellow("No API key source set; LC_API_KEY, --api-key-file or a prompt must supply it.")
+	}
+	if cfg.CurrentProfile == name {
+		color.Blue("Current profile: %s", name)
+	}
+	return nil
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return err
+	}
+	if len(cfg.Profiles) == 0 {
+		color.Yellow("No profiles configured. Add one with: lc-sensors profile add NAME --oid ORG_ID --api-key-env VAR")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"", "Name", "OID", "Key Source", "Endpoint", "Defaults"})
+	table.SetBorder(false)
+
+	for _, name := range cfg.Names() {
+		p := cfg.Profiles[name]
+		current := ""
+		if name == cfg.CurrentProfile {
+			current = "*"
+		}
+		var defaults []string
+		if p.Theme != "" {
+			defaults = append(defaults, "theme="+p.Theme)
+		}
+		if p.Output != "" {
+			defaults = append(defaults, "output="+p.Output)
+		}
+		if p.Concurrency > 0 {
+			defaults = append(defaults, fmt.Sprintf("concurrency=%d", p.Concurrency))
+		}
+		table.Append([]string{current, name, p.OID, p.KeySource(), p.Endpoint, strings.Join(defaults, ", ")})
+	}
+
+	table.Render()
+	return nil
+}
+
+func runProfileUse(cmd *cobra.Command, args []string) error {
+	path := config.DefaultPath()
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.Profile(args[0]); err != nil {
+		return err
+	}
+
+	cfg.CurrentProfile = args[0]
+	if err := cfg.Save(path); err != nil {
+		return err
+	}
+	color.Green("Current profile: %s", args[0])
+	return nil
+}
+
+func runProfileRemove(cmd *cobra.Command, args []string) error {
+	path := config.DefaultPath()
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.Profile(args[0]); err != nil {
+		return err
+	}
+
+	delete(cfg.Profiles, args[0])
+	if cfg.CurrentProfile == args[0] {
+		cfg.CurrentProfile = ""
+	}
+	if err := cfg.Save(path); err != nil {
+		return err
+	}
+	color.Green("Removed profile %q", args[0])
+	return nil
+}
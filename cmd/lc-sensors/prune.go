@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"LC_utils/internal/api"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Prune command flags
+	pruneOfflineFor   string
+	pruneApply        bool
+	pruneReport       string
+	pruneReportFormat string
+)
+
+// minPruneAge guards against deleting sensors that are merely rebooting
+// or between shifts.
+const minPruneAge = 24 * time.Hour
+
+// pruneRecord is one deleted sensor in the prune report.
+type pruneRecord struct {
+	Profile   string `json:"profile,omitempty"`
+	OID       string `json:"oid"`
+	SID       string `json:"sid"`
+	Hostname  string `json:"hostname"`
+	Platform  string `json:"platform"`
+	LastSeen  string `json:"last_seen"`
+	DeletedAt string `json:"deleted_at"`
+}
+
+// newPruneCmd returns the `prune` command, which deletes sensors that
+// have been offline for a long time.
+func newPruneCmd() *cobra.Command {
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete sensors that have been offline for a long time",
+		Long: `Find sensors that have not been seen for at least --offline-for, such as
+reimaged or decommissioned machines, and delete them.
+
+By default prune only shows the sensors it would delete. Pass --apply to
+delete them after confirmation. Every deleted sensor is recorded in a
+JSON or CSV report (--report, by default lc-sensors-prune-<time>.json in
+the current directory). Online sensors and sensors without a last-seen
+time are never deleted.
+
+Example:
+  # Show the Windows sensors offline for 30 days or more
+  lc-sensors prune --offline-for 30d --filter-platform windows
+
+  # Delete them and keep a CSV record
+  lc-sensors prune --offline-for 30d --filter-platform windows --apply --report pruned.csv --report-format csv`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireCredentials(); err != nil {
+				return err
+			}
+			age, err := parseAge(pruneOfflineFor)
+			if err != nil {
+				return fmt.Errorf("--offline-for: %w", err)
+			}
+			if age < minPruneAge {
+				return fmt.Errorf("--offline-for must be at least %s", formatAge(minPruneAge))
+			}
+			switch pruneReportFormat {
+			case "json", "csv":
+			default:
+				return fmt.Errorf("--report-format must be json or csv")
+			}
+			return prepareFilters()
+		},
+		Run: runPrune,
+	}
+
+	pruneCmd.Flags().StringVar(&pruneOfflineFor, "offline-for", "", "Minimum time since the sensor was last seen, e.g. 30d, 2w or 36h")
+	pruneCmd.Flags().BoolVar(&pruneApply, "apply", false, "Delete the sensors instead of only listing them")
+	pruneCmd.Flags().StringVar(&pruneReport, "report", "", "File recording the deleted sensors (default lc-sensors-prune-<time>.<format>)")
+	pruneCmd.Flags().StringVar(&pruneReportFormat, "report-format", "json", "Report format (json, csv)")
+	addSelectionFlags(pruneCmd)
+	addFanOutFlags(pruneCmd)
+	addOrgFlags(pruneCmd)
+	_ = pruneCmd.MarkFlagRequired("offline-for")
+	return pruneCmd
+}
+
+func runPrune(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	age, _ := parseAge(pruneOfflineFor)
+	now := time.Now()
+	cutoff := now.Add(-age)
+
+	// Print banner
+	fmt.Print(printBanner())
+
+	// Initialize API clients, one per organization
+	orgs, exitStatus := connectOrgs(cmd)
+
+	// Select the offline sensors last seen before the cutoff
+	color.Blue("Retrieving sensors...")
+	opts := &api.ListOptions{
+		WithTags: needTags(),
+	}
+	var unknown atomic.Int64
+	stale, failedOrgs := selectSensors(ctx, orgs, opts, func(sensor api.Sensor) bool {
+		if sensor.IsOnline || !sensorMatchesFilters(sensor) {
+			return false
+		}
+		lastSeen, ok := sensor.LastSeenTime()
+		if !ok {
+			unknown.Add(1)
+			return false
+		}
+		return lastSeen.Before(cutoff)
+	})
+	if n := unknown.Load(); n > 0 {
+		color.Yellow("Skipping %d offline sensors without a last-seen time", n)
+	}
+
+	if len(stale) == 0 {
+		color.Yellow("No sensors offline for %s or more", pruneOfflineFor)
+		exitWithOrgSummary(ctx, orgs, nil, nil, failedOrgs, exitStatus)
+	}
+
+	color.Yellow("\nFound %d sensors offline for %s or more:", len(stale), pruneOfflineFor)
+	printStaleSensors(stale, now)
+
+	if !pruneApply {
+		fmt.Println()
+		color.Yellow("Dry run: no sensors were deleted. Re-run with --apply to delete them.")
+		exitWithOrgSummary(ctx, orgs, stale, nil, failedOrgs, exitStatus)
+	}
+
+	// Open the report first, so that a bad path fails before anything is
+	// deleted
+	reportPath := pruneReport
+	if reportPath == "" {
+		reportPath = fmt.Sprintf("lc-sensors-prune-%s.%s", now.Format("20060102-150405"), pruneReportFormat)
+	}
+	report, err := os.OpenFile(reportPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		fatal("Failed to create report", err)
+	}
+
+	if !confirm(ctx, fmt.Sprintf("\nDo you want to permanently delete these %d sensors? [y/N] ", len(stale))) {
+		report.Close()
+		os.Remove(reportPath)
+		color.Yellow("Operation cancelled")
+		os.Exit(0)
+	}
+
+	// Delete the sensors, recording each one that is gone
+	color.Blue("\nDeleting sensors...")
+	var (
+		recordsMu sync.Mutex
+		records   []pruneRecord
+		failCount atomic.Int64
+	)
+	results := runOnSensors(ctx, stale, func(ctx context.Context, org *orgClient, sensor api.Sensor) error {
+		if err := org.client.DeleteSensor(ctx, sensor.SID); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			printLocked(func() {
+				color.Red("%sFailed to delete sensor %s (%s): %v", org.prefix(), sensor.Hostname, sensor.SID, err)
+			})
+			failCount.Add(1)
+			return err
+		}
+		record := pruneRecord{
+			OID:       org.target.OID,
+			SID:       sensor.SID,
+			Hostname:  sensor.Hostname,
+			Platform:  sensor.GetPlatformString(),
+			LastSeen:  sensor.LastSeen,
+			DeletedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		if multiOrg() {
+			record.Profile = org.target.Profile
+		}
+		recordsMu.Lock()
+		records = append(records, record)
+		recordsMu.Unlock()
+		printLocked(func() {
+			color.Green("%sDeleted sensor %s (%s)", org.prefix(), sensor.Hostname, sensor.SID)
+		})
+		return nil
+	})
+	skippedCount := countSkipped(ctx, results)
+	code := combineExitCodes(exitStatus, resultsExitCode(ctx, results))
+
+	// Write the report, even when interrupted, so every deletion is logged
+	err = writePruneReport(report, pruneReportFormat, records)
+	if closeErr := report.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		color.Red("Failed to write report %s: %v", reportPath, err)
+		code = combineExitCodes(code, exitError)
+	}
+
+	// Print summary
+	printFailedSensors(ctx, stale, results)
+	fmt.Println()
+	color.Green("Deleted %d sensors", len(records))
+	if failCount.Load() > 0 {
+		color.Red("Failed to delete %d sensors", failCount.Load())
+	}
+	if err == nil {
+		color.Blue("Report written to %s", reportPath)
+	}
+	printRetrySummary(orgsClients(orgs)...)
+	printCancelled(ctx, skippedCount, "sensors")
+	exitWithOrgSummary(ctx, orgs, stale, results, failedOrgs, code)
+}
+
+// printStaleSensors shows the sensors selected for pruning with the time
+// since they were last seen.
+func printStaleSensors(sensors []targetSensor, now time.Time) {
+	table := tablewriter.NewWriter(os.Stdout)
+	header := []string{"SID", "Hostname", "Platform", "Last Seen", "Offline For", "Tags"}
+	if multiOrg() {
+		header = append([]string{"Org"}, header...)
+	}
+	table.SetHeader(header)
+	table.SetBorder(false)
+
+	for _, ts := range sensors {
+		sensor := ts.sensor
+		lastSeen, _ := sensor.LastSeenTime()
+		var row []string
+		if multiOrg() {
+			row = []string{ts.org.target.Label}
+		}
+		table.Append(append(row,
+			sensor.SID,
+			sensor.Hostname,
+			sensor.GetPlatformString(),
+			lastSeen.In(displayLocation).Format("2006-01-02 15:04:05 MST"),
+			formatAge(now.Sub(lastSeen)),
+			strings.Join(sensor.Tags, ", "),
+		))
+	}
+	table.Render()
+}
+
+// writePruneReport writes the deleted sensors, ordered by organization
+// and hostname, as a JSON array or CSV.
+//
+// Parameters:
+//   - w: Destination of the report
+//   - format: "json" or "csv"
+//   - records: The deleted sensors
+//
+// Returns:
+//   - error: Any error writing the report
+func writePruneReport(w io.Writer, format string, records []pruneRecord) error {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].OID != records[j].OID {
+			return records[i].OID < records[j].OID
+		}
+		return records[i].Hostname < records[j].Hostname
+	})
+
+	if format == "csv" {
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"Profile", "OID", "SID", "Hostname", "Platform", "Last Seen", "Deleted At"})
+		for _, r := range records {
+			cw.Write([]string{r.Profile, r.OID, r.SID, r.Hostname, r.Platform, r.LastSeen, r.DeletedAt})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if records == nil {
+		records = []pruneRecord{}
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format JSON: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
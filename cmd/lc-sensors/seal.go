@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+
+	"LC_utils/internal/api"
+
+	"github.com/spf13/cobra"
+)
+
+// newSealCmd returns the `seal` command.
+func newSealCmd() *cobra.Command {
+	return newStateChangeCmd(&cobra.Command{
+		Use:   "seal",
+		Short: "Seal sensors matching filters against tampering",
+		Long: `Seal sensors that match the specified filters. A sealed sensor cannot be
+stopped or uninstalled locally, even by an administrator.
+
+Example:
+  # Seal every production Windows sensor
+  lc-sensors seal --filter-tag production --filter-platform windows
+
+  # Audit tamper protection coverage first
+  lc-sensors list --filter-unsealed`,
+	}, sensorStateChange{
+		verb:       "seal",
+		done:       "sealed",
+		skipReason: "already sealed",
+		want:       true,
+		current:    isSealed,
+		apply: func(ctx context.Context, client *api.Client, sensorID string) error {
+			return client.SealSensor(ctx, sensorID)
+		},
+	})
+}
+
+// newUnsealCmd returns the `unseal` command.
+func newUnsealCmd() *cobra.Command {
+	return newStateChangeCmd(&cobra.Command{
+		Use:   "unseal",
+		Short: "Unseal sensors matching filters",
+		Long: `Unseal sensors that match the specified filters, for example before
+uninstalling or upgrading them locally.
+
+Example:
+  lc-sensors unseal --filter-hostname "build-*"`,
+	}, sensorStateChange{
+		verb:       "unseal",
+		done:       "unsealed",
+		skipReason: "not sealed",
+		want:       false,
+		current:    isSealed,
+		apply: func(ctx context.Context, client *api.Client, sensorID string) error {
+			return client.UnsealSensor(ctx, sensorID)
+		},
+	})
+}
+
+// isSealed reads the seal state of a sensor.
+func isSealed(sensor api.Sensor) bool {
+	return sensor.IsSealed
+}
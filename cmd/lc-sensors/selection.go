@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/match"
+	"LC_utils/internal/selector"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// filterRegex makes --filter-hostname and --filter-tag regular
+	// expressions instead of globs
+	filterRegex bool
+
+	// platformFilter holds the platforms of --filter-platform; nil when unset
+	platformFilter map[api.Platform]bool
+
+	// Patterns compiled from --filter-hostname and --filter-tag; nil when unset
+	hostnamePattern *match.Pattern
+	tagPattern      *match.Pattern
+
+	// Address filter flags and their parsed sets; nil when unset
+	filterIntCIDR string
+	filterExtCIDR string
+	intAddrs      *match.AddrSet
+	extAddrs      *match.AddrSet
+
+	// selectExpr is the --select flag; sensorSelector is its parsed form
+	selectExpr     string
+	sensorSelector *selector.Selector
+)
+
+// Help texts of the hostname and tag filters, shared by every command
+const (
+	hostnameFilterUsage = `Filter by hostname: comma-separated globs matching the whole name, e.g. "web-*,db-0?" (see --regex)`
+	tagFilterUsage      = `Filter by tag: comma-separated globs, e.g. "prod-*" (see --regex)`
+	regexFilterUsage    = "Treat --filter-hostname and --filter-tag as regular expressions"
+	platformFilterUsage = "Filter by platform: comma-separated names such as windows, macos (mac, osx), linux, chrome or aws"
+)
+
+// addSelectionFlags registers the sensor filters of the commands that
+// change a selection of sensors: --filter-hostname, --filter-tag,
+// --filter-platform, the address filters, --select and the SID lists.
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&filterHostname, "filter-hostname", "", hostnameFilterUsage)
+	cmd.Flags().StringVar(&filterTag, "filter-tag", "", tagFilterUsage)
+	cmd.Flags().BoolVar(&filterRegex, "regex", false, regexFilterUsage)
+	cmd.Flags().StringVar(&filterPlatform, "filter-platform", "", platformFilterUsage)
+	addAddrFilterFlags(cmd)
+	addSelectFlag(cmd)
+	addSidsFlags(cmd)
+}
+
+// addAddrFilterFlags registers --int-cidr and --ext-cidr, which filter
+// sensors by internal and external IP address.
+func addAddrFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&filterIntCIDR, "int-cidr", "", "Filter by internal IP: comma-separated CIDRs, ranges or addresses, e.g. 10.0.0.0/8,192.168.1.10-192.168.1.50")
+	cmd.Flags().StringVar(&filterExtCIDR, "ext-cidr", "", "Filter by external IP: comma-separated CIDRs, ranges or addresses, IPv4 or IPv6")
+}
+
+// addSelectFlag registers --select, which selects sensors with a
+// selector expression.
+func addSelectFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&selectExpr, "select", "", `Select sensors with an expression, e.g. 'plat == windows and tag matches "prod-*" and not online'`)
+}
+
+// compileSelector parses --select. Dates and ages in the expression are
+// read like the time filters.
+func compileSelector() error {
+	if selectExpr == "" {
+		return nil
+	}
+	now := time.Now()
+	sel, err := selector.Parse(selectExpr, &selector.Options{
+		ParseTime: func(value string) (time.Time, error) {
+			return parseTimeSpec(value, now)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("--select: %w", err)
+	}
+	sensorSelector = sel
+	return nil
+}
+
+// requireTargetFilter returns an error unless --filter-hostname,
+// --filter-tag, --int-cidr, --ext-cidr, --select, --sids or --sids-file
+// is set, so that a command never acts on a whole organization by
+// accident.
+func requireTargetFilter() error {
+	if filterHostname == "" && filterTag == "" && filterIntCIDR == "" && filterExtCIDR == "" && selectExpr == "" && len(sidsList) == 0 && sidsFile == "" {
+		return fmt.Errorf("one of --filter-hostname, --filter-tag, --int-cidr, --ext-cidr, --select, --sids or --sids-file is required")
+	}
+	return nil
+}
+
+// needTags reports whether sensors must be listed with their tags to
+// apply the filters.
+func needTags() bool {
+	return filterTag != "" || (sensorSelector != nil && sensorSelector.Uses("tag"))
+}
+
+// validateSelection checks the selection flags. A hostname, tag, address,
+// --select or SID list filter is required.
+func validateSelection() error {
+	if err := requireTargetFilter(); err != nil {
+		return err
+	}
+	return prepareFilters()
+}
+
+// prepareFilters validates and compiles every sensor filter once, before
+// any sensor is listed: the platforms, hostname and tag patterns,
+// address sets, time filters and --select are parsed, and the SID lists
+// are read.
+func prepareFilters() error {
+	if err := parsePlatformFilter(); err != nil {
+		return err
+	}
+	if err := compileNameFilters(); err != nil {
+		return err
+	}
+	if err := parseAddrFilters(); err != nil {
+		return err
+	}
+	if err := parseTimeFilters(); err != nil {
+		return err
+	}
+	if err := compileSelector(); err != nil {
+		return err
+	}
+	return loadSensorRefs()
+}
+
+// compileNameFilters compiles --filter-hostname and --filter-tag.
+func compileNameFilters() error {
+	var err error
+	if filterHostname != "" {
+		if hostnamePattern, err = match.Compile(filterHostname, filterRegex); err != nil {
+			return fmt.Errorf("--filter-hostname: %w", err)
+		}
+	}
+	if filterTag != "" {
+		if tagPattern, err = match.Compile(filterTag, filterRegex); err != nil {
+			return fmt.Errorf("--filter-tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// parsePlatformFilter parses --filter-platform. Names, display names
+// such as macOS and aliases such as mac or win are accepted.
+func parsePlatformFilter() error {
+	if filterPlatform == "" {
+		return nil
+	}
+	platformFilter = map[api.Platform]bool{}
+	for _, name := range strings.Split(filterPlatform, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		platform, err := api.ParsePlatform(name)
+		if err != nil {
+			return fmt.Errorf("--filter-platform: %w", err)
+		}
+		platformFilter[platform] = true
+	}
+	if len(platformFilter) == 0 {
+		return fmt.Errorf("--filter-platform: no platform given")
+	}
+	return nil
+}
+
+// selectAndConfirm lists the sensors of every organization that match
+// the selection flags, shows them and asks the user to confirm the
+// action. Matching sensors rejected by keep, typically because they are
+// already in the requested state, are counted and left out. It exits if
+// nothing is left or the user declines.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation
+//   - orgs: The connected organizations
+//   - exitStatus: Exit code of the connection step, used when exiting early
+//   - keep: Decides which matching sensors to act on, or nil for all
+//   - skipReason: Why sensors rejected by keep are skipped, e.g. "already isolated"
+//   - action: The verb of the confirmation question, e.g. "isolate"
+//
+// Returns:
+//   - []targetSensor: The confirmed selection
+//   - map[*orgClient]error: Listing errors of the organizations that failed
+func selectAndConfirm(ctx context.Context, orgs []*orgClient, exitStatus int, keep func(api.Sensor) bool, skipReason, action string) ([]targetSensor, map[*orgClient]error) {
+	color.Blue("Retrieving sensors...")
+	opts := &api.ListOptions{
+		WithTags: true, // We need tags for filtering
+	}
+	matched, failedOrgs := selectSensors(ctx, orgs, opts, sensorMatchesFilters)
+
+	var selected []targetSensor
+	for _, ts := range matched {
+		if keep == nil || keep(ts.sensor) {
+			selected = append(selected, ts)
+		}
+	}
+	if skipped := len(matched) - len(selected); skipped > 0 {
+		color.Blue("Skipping %d matching sensors that are %s", skipped, skipReason)
+	}
+
+	if len(selected) == 0 {
+		color.Yellow("No sensors to %s", action)
+		exitWithOrgSummary(ctx, orgs, nil, nil, failedOrgs, exitStatus)
+	}
+
+	// Confirm with user
+	color.Yellow("\nFound %d sensors matching filters:", len(selected))
+	for _, ts := range selected {
+		sensor := ts.sensor
+		fmt.Printf("- %s%s (%s) [Platform: %s]\n", ts.org.prefix(), sensor.Hostname, sensor.SID, sensor.GetPlatformString())
+	}
+
+	if !confirm(ctx, fmt.Sprintf("\nDo you want to %s these sensors? [y/N] ", action)) {
+		color.Yellow("Operation cancelled")
+		os.Exit(0)
+	}
+	return selected, failedOrgs
+}
+
+// parseAddrFilters parses --int-cidr and --ext-cidr.
+func parseAddrFilters() error {
+	var err error
+	if filterIntCIDR != "" {
+		if intAddrs, err = match.ParseAddrSet(filterIntCIDR); err != nil {
+			return fmt.Errorf("--int-cidr: %w", err)
+		}
+	}
+	if filterExtCIDR != "" {
+		if extAddrs, err = match.ParseAddrSet(filterExtCIDR); err != nil {
+			return fmt.Errorf("--ext-cidr: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"LC_utils/internal/api"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// SID list flags: --sids takes SIDs or hostnames, or "-" for stdin
+	sidsList []string
+	sidsFile string
+
+	// sensorRefs is the parsed SID list; nil when neither flag is set
+	sensorRefs *sensorRefSet
+
+	// stdinUsed is set once stdin has been read as a SID list, so that
+	// confirmations read the terminal instead
+	stdinUsed bool
+)
+
+// addSidsFlags registers --sids and --sids-file, which restrict a
+// command to a saved list of sensors.
+func addSidsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&sidsList, "sids", nil, `Only these sensors: comma-separated SIDs or hostnames, or "-" to read a list from stdin`)
+	cmd.Flags().StringVar(&sidsFile, "sids-file", "", "Only the sensors listed in this file: SIDs or hostnames one per line, or the JSON or CSV output of list")
+}
+
+// sensorRef is one entry of a SID list. Entries from list output may
+// name the organization of the sensor.
+type sensorRef struct {
+	sid      string
+	hostname string
+	oid      string
+	// source is the entry as shown in warnings
+	source  string
+	matched bool
+}
+
+// sensorRefSet is a SID list indexed by SID and hostname. It is safe
+// for concurrent use.
+type sensorRefSet struct {
+	mu         sync.Mutex
+	refs       []*sensorRef
+	bySID      map[string][]*sensorRef
+	byHostname map[string][]*sensorRef
+}
+
+// newSensorRefSet indexes the entries of a SID list, dropping duplicates.
+func newSensorRefSet(refs []*sensorRef) *sensorRefSet {
+	s := &sensorRefSet{
+		bySID:      map[string][]*sensorRef{},
+		byHostname: map[string][]*sensorRef{},
+	}
+	seen := map[[3]string]bool{}
+	for _, ref := range refs {
+		key := [3]string{strings.ToLower(ref.sid), strings.ToLower(ref.hostname), strings.ToLower(ref.oid)}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		s.refs = append(s.refs, ref)
+		if ref.sid != "" {
+			s.bySID[key[0]] = append(s.bySID[key[0]], ref)
+		} else {
+			s.byHostname[key[1]] = append(s.byHostname[key[1]], ref)
+		}
+	}
+	return s
+}
+
+// Match reports whether a sensor is in the list: its SID is listed, or
+// its hostname is listed without a SID. Entries naming an organization
+// only match sensors of that organization.
+func (s *sensorRefSet) Match(sensor api.Sensor) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := false
+	for _, refs := range [][]*sensorRef{s.bySID[strings.ToLower(sensor.SID)], s.byHostname[strings.ToLower(sensor.Hostname)]} {
+		for _, ref := range refs {
+			if ref.oid != "" && sensor.OID != "" && !strings.EqualFold(ref.oid, sensor.OID) {
+				continue
+			}
+			ref.matched = true
+			found = true
+		}
+	}
+	return found
+}
+
+// Unmatched returns the entries that matched no sensor so far.
+func (s *sensorRefSet) Unmatched() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var unmatched []string
+	for _, ref := range s.refs {
+		if !ref.matched {
+			unmatched = append(unmatched, ref.source)
+		}
+	}
+	return unmatched
+}
+
+// loadSensorRefs reads --sids and --sids-file.
+func loadSensorRefs() error {
+	if len(sidsList) == 0 && sidsFile == "" {
+		return nil
+	}
+
+	var refs []*sensorRef
+	readFrom := func(flag, path string) error {
+		r := io.Reader(os.Stdin)
+		if path == "-" {
+			// Stdin can only be read once
+			if stdinUsed {
+				return nil
+			}
+			stdinUsed = true
+		} else {
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", flag, err)
+			}
+			defer f.Close()
+			r = f
+		}
+		parsed, err := parseSensorRefs(r)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", flag, path, err)
+		}
+		if len(parsed) == 0 {
+			return fmt.Errorf("%s %s: no SIDs or hostnames found", flag, path)
+		}
+		refs = append(refs, parsed...)
+		return nil
+	}
+
+	for _, item := range sidsList {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "-":
+			if err := readFrom("--sids", "-"); err != nil {
+				return err
+			}
+		case item != "":
+			refs = append(refs, newSensorRef(item))
+		}
+	}
+	if sidsFile != "" {
+		if err := readFrom("--sids-file", sidsFile); err != nil {
+			return err
+		}
+	}
+	if len(refs) == 0 {
+		return fmt.Errorf("--sids: no SIDs or hostnames given")
+	}
+	sensorRefs = newSensorRefSet(refs)
+	return nil
+}
+
+// newSensorRef returns the entry for a SID or hostname.
+func newSensorRef(value string) *sensorRef {
+	ref := &sensorRef{source: value}
+	if isSensorID(value) {
+		ref.sid = value
+	} else {
+		ref.hostname = value
+	}
+	return ref
+}
+
+// parseSensorRefs reads a SID list in any of the supported formats,
+// chosen from its content:
+//   - JSON: the output of list -f json, a stream of sensor objects such as
+//     jq '.[]' prints, or an array of SID and hostname strings
+//   - CSV: the output of list -f csv, recognized by its SID header column
+//   - Text: SIDs or hostnames separated by newlines, commas or spaces,
+//     with # starting a comment
+func parseSensorRefs(r io.Reader) ([]*sensorRef, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	switch trimmed[0] {
+	case '[', '{', '"':
+		return parseSensorRefsJSON(trimmed)
+	}
+	firstLine, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	if header, err := csv.NewReader(bytes.NewReader(firstLine)).Read(); err == nil && csvColumn(header, "SID") >= 0 {
+		return parseSensorRefsCSV(trimmed)
+	}
+	return parseSensorRefsText(trimmed), nil
+}
+
+// sensorRefJSON is the part of a listed sensor that identifies it.
+// api.Sensor is not used as it also parses the sensor's times.
+type sensorRefJSON struct {
+	SID      string `json:"sid"`
+	Hostname string `json:"hostname"`
+	OID      string `json:"oid"`
+}
+
+// parseSensorRefsJSON reads one or more JSON values, each a sensor
+// object, a SID or hostname string, or an array of those.
+func parseSensorRefsJSON(data []byte) ([]*sensorRef, error) {
+	var refs []*sensorRef
+	var add func(raw json.RawMessage) error
+	add = func(raw json.RawMessage) error {
+		switch bytes.TrimSpace(raw)[0] {
+		case '[':
+			var items []json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return err
+			}
+			for _, item := range items {
+				if err := add(item); err != nil {
+					return err
+				}
+			}
+		case '"':
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return err
+			}
+			if value = strings.TrimSpace(value); value != "" {
+				refs = append(refs, newSensorRef(value))
+			}
+		case '{':
+			var s sensorRefJSON
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return err
+			}
+			switch {
+			case s.SID != "":
+				refs = append(refs, &sensorRef{sid: s.SID, hostname: s.Hostname, oid: s.OID, source: s.SID})
+			case s.Hostname != "":
+				refs = append(refs, &sensorRef{hostname: s.Hostname, oid: s.OID, source: s.Hostname})
+			default:
+				return fmt.Errorf("sensor object without a sid or hostname: %s", raw)
+			}
+		default:
+			return fmt.Errorf("expected sensors, SIDs or hostnames but found %s", raw)
+		}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return refs, nil
+			}
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if err := add(raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+}
+
+// parseSensorRefsCSV reads CSV with a header row naming a SID column
+// and optionally Hostname and OID columns, as written by list -f csv.
+func parseSensorRefsCSV(data []byte) ([]*sensorRef, error) {
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	header := records[0]
+	sidCol, hostCol, oidCol := csvColumn(header, "SID"), csvColumn(header, "Hostname"), csvColumn(header, "OID")
+	field := func(record []string, col int) string {
+		if col < 0 || col >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[col])
+	}
+
+	var refs []*sensorRef
+	for _, record := range records[1:] {
+		sid, hostname, oid := field(record, sidCol), field(record, hostCol), field(record, oidCol)
+		switch {
+		case sid != "":
+			refs = append(refs, &sensorRef{sid: sid, hostname: hostname, oid: oid, source: sid})
+		case hostname != "":
+			refs = append(refs, &sensorRef{hostname: hostname, oid: oid, source: hostname})
+		}
+	}
+	return refs, nil
+}
+
+// csvColumn returns the index of a header column, ignoring case, or -1.
+func csvColumn(header []string, name string) int {
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseSensorRefsText reads SIDs and hostnames separated by newlines,
+// commas or whitespace, skipping # comments. Lines are split in memory
+// rather than scanned so that no line is too long to read.
+func parseSensorRefsText(data []byte) []*sensorRef {
+	var refs []*sensorRef
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ := strings.Cut(line, "#")
+		for _, value := range strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		}) {
+			refs = append(refs, newSensorRef(value))
+		}
+	}
+	return refs
+}
+
+// warnUnmatchedRefs reports, on stderr, the entries of the SID list that
+// matched no sensor.
+func warnUnmatchedRefs() {
+	if sensorRefs == nil {
+		return
+	}
+	unmatched := sensorRefs.Unmatched()
+	if len(unmatched) == 0 {
+		return
+	}
+	const shown = 10
+	list := unmatched
+	if len(list) > shown {
+		list = list[:shown]
+	}
+	msg := fmt.Sprintf("%d listed sensors were not found: %s", len(unmatched), strings.Join(list, ", "))
+	if len(unmatched) > shown {
+		msg += fmt.Sprintf(" and %d more", len(unmatched)-shown)
+	}
+	fmt.Fprintln(os.Stderr, color.YellowString(msg))
+}
+
+// terminalInput opens the terminal for reading, for confirmations when
+// stdin carries a SID list.
+func terminalInput() (*os.File, error) {
+	path := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		path = "CONIN$"
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("stdin is used by --sids - and no terminal is available to confirm: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/snapshot"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Snapshot command flags
+	snapshotDir    string
+	snapshotName   string
+	snapshotForce  bool
+	snapshotOutput string
+)
+
+// liveRef is the snapshot reference for the sensors as they are now.
+const liveRef = "live"
+
+// newSnapshotCmd returns the `snapshot` command and its subcommands.
+func newSnapshotCmd() *cobra.Command {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save sensor inventories and compare them",
+		Long: `Save the full sensor list, with tags, to a local file and compare snapshots
+with each other or with the live sensor list.
+
+Snapshots are stored in ` + snapshot.DefaultDir() + `
+(set LC_SENSORS_SNAPSHOTS or --dir to use another directory).
+
+Example:
+  # Weekly change report as markdown, against last week's snapshot
+  lc-sensors snapshot diff latest -f markdown > changes.md
+  lc-sensors snapshot save`,
+	}
+	snapshotCmd.PersistentFlags().StringVar(&snapshotDir, "dir", snapshot.DefaultDir(), "Snapshot directory")
+
+	saveCmd := &cobra.Command{
+		Use:   "save",
+		Short: "Save the current sensor list as a snapshot",
+		Long: `List every sensor with its tags and save them as a snapshot named --name,
+or after the current time.
+
+Example:
+  lc-sensors snapshot save --name before-upgrade
+  lc-sensors snapshot save --all-profiles`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireCredentials(); err != nil {
+				return err
+			}
+			if snapshotName != "" {
+				return snapshot.ValidateName(snapshotName)
+			}
+			return nil
+		},
+		Run: runSnapshotSave,
+	}
+	saveCmd.Flags().StringVar(&snapshotName, "name", "", "Snapshot name (default: the current time, e.g. 2026-10-16T091500Z)")
+	saveCmd.Flags().BoolVar(&snapshotForce, "force", false, "Replace an existing snapshot with the same name")
+	addOrgFlags(saveCmd)
+
+	diffCmd := &cobra.Command{
+		Use:   "diff A [B]",
+		Short: "Show the changes between two snapshots",
+		Long: `Show the sensors added and removed between snapshot A and snapshot B, and
+the hostname, IP address, version, isolation and tag changes of the others.
+
+A and B are snapshot names, snapshot files, "latest" for the most recent
+snapshot, or "live" for the current sensor list, which is the default
+for B.
+
+Example:
+  # What changed since the snapshot named before-upgrade
+  lc-sensors snapshot diff before-upgrade
+
+  # Changes between two saved snapshots, as JSON
+  lc-sensors snapshot diff 2026-10-09T090000Z 2026-10-16T090000Z -f json`,
+		Args: cobra.RangeArgs(1, 2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch snapshotOutput {
+			case "text", "json", "markdown", "md":
+			default:
+				return fmt.Errorf("--output must be one of: text, json, markdown")
+			}
+			if len(args) == 1 || args[0] == liveRef || args[1] == liveRef {
+				return requireCredentials()
+			}
+			return nil
+		},
+		Run: runSnapshotDiff,
+	}
+	diffCmd.Flags().StringVarP(&snapshotOutput, "output", "f", "text", "Output format (text/json/markdown)")
+	addOrgFlags(diffCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List saved snapshots",
+		Args:  cobra.NoArgs,
+		Run:   runSnapshotList,
+	}
+
+	snapshotCmd.AddCommand(saveCmd, diffCmd, listCmd)
+	return snapshotCmd
+}
+
+func runSnapshotSave(cmd *cobra.Command, args []string) {
+	fmt.Print(printBanner())
+
+	color.Blue("Retrieving sensors...")
+	snap, exitStatus := liveSnapshot(cmd)
+	if snapshotName != "" {
+		snap.Name = snapshotName
+	}
+
+	path, err := snapshot.Save(snapshotDir, snap, snapshotForce)
+	if err != nil {
+		fatal("Failed to save snapshot", err)
+	}
+	if multiOrg() {
+		color.Green("\nSaved %d sensors of %d organizations as snapshot %q", len(snap.Sensors), len(snap.Orgs), snap.Name)
+	} else {
+		color.Green("\nSaved %d sensors as snapshot %q", len(snap.Sensors), snap.Name)
+	}
+	fmt.Printf("File: %s\n", path)
+	if exitStatus != exitOK {
+		os.Exit(exitStatus)
+	}
+}
+
+// liveSnapshot lists the sensors of every organization as a snapshot
+// named after the current time. It exits if any organization cannot be
+// listed, as a partial inventory would show its sensors as removed.
+//
+// Parameters:
+//   - cmd: The running command
+//
+// Returns:
+//   - *snapshot.Snapshot: The current sensors
+//   - int: Exit code of the connection step
+func liveSnapshot(cmd *cobra.Command) (*snapshot.Snapshot, int) {
+	ctx := cmd.Context()
+	orgs, exitStatus := connectOrgs(cmd)
+	if multiOrg() && exitStatus != exitOK {
+		color.Red("Not every organization could be reached; a snapshot must cover all of them")
+		os.Exit(exitStatus)
+	}
+
+	now := time.Now()
+	selected, failedOrgs := selectSensors(ctx, orgs, &api.ListOptions{WithTags: true}, func(api.Sensor) bool { return true })
+	if len(failedOrgs) > 0 {
+		color.Red("Not every organization could be listed; a snapshot must cover all of them")
+		os.Exit(failedOrgsExitCode(exitStatus, failedOrgs))
+	}
+
+	snap := &snapshot.Snapshot{
+		Name:      snapshot.DefaultName(now),
+		CreatedAt: now.UTC(),
+		Sensors:   make([]api.Sensor, 0, len(selected)),
+	}
+	for _, org := range orgs {
+		snap.Orgs = append(snap.Orgs, org.target.OID)
+	}
+	for _, ts := range selected {
+		sensor := ts.sensor
+		if sensor.OID == "" {
+			sensor.OID = ts.org.target.OID
+		}
+		snap.Sensors = append(snap.Sensors, sensor)
+	}
+	return snap, exitStatus
+}
+
+func runSnapshotDiff(cmd *cobra.Command, args []string) {
+	text := snapshotOutput == "text"
+	if text {
+		fmt.Print(printBanner())
+	}
+
+	refs := append(args, liveRef)[:2]
+	snaps := make([]*snapshot.Snapshot, 2)
+	exitStatus := exitOK
+	for i, ref := range refs {
+		if ref == liveRef {
+			if text {
+				color.Blue("Retrieving sensors...")
+			}
+			snaps[i], exitStatus = liveSnapshot(cmd)
+			snaps[i].Name = liveRef
+			continue
+		}
+		path, err := snapshot.Resolve(snapshotDir, ref)
+		if err != nil {
+			fatal("Failed to find snapshot", err)
+		}
+		if snaps[i], err = snapshot.Load(path); err != nil {
+			fatal("Failed to read snapshot", err)
+		}
+	}
+
+	diff := snapshot.Compare(snaps[0], snaps[1])
+	var err error
+	switch snapshotOutput {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diff)
+	case "markdown", "md":
+		err = writeDiffMarkdown(os.Stdout, diff)
+	default:
+		writeDiffText(diff)
+	}
+	if err != nil {
+		fatal("Failed to write output", err)
+	}
+	if exitStatus != exitOK {
+		os.Exit(exitStatus)
+	}
+}
+
+func runSnapshotList(cmd *cobra.Command, args []string) {
+	infos, err := snapshot.List(snapshotDir)
+	if err != nil {
+		fatal("Failed to list snapshots", err)
+	}
+	if len(infos) == 0 {
+		color.Yellow("No snapshots in %s", snapshotDir)
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "Created", "Orgs", "Sensors"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	for _, info := range infos {
+		table.Append([]string{info.Name, formatSensorTime(info.CreatedAt, true, ""), fmt.Sprint(info.Orgs), fmt.Sprint(info.Sensors)})
+	}
+	table.Render()
+	color.Blue("\nSnapshots in %s", snapshotDir)
+}
+
+// diffTitle describes the two sides of a diff, e.g. "before-upgrade
+// (2026-10-09 09:00:00 UTC (7d ago)) to live".
+func diffTitle(d *snapshot.Diff) string {
+	side := func(name string, at time.Time) string {
+		if name == liveRef {
+			return liveRef
+		}
+		return fmt.Sprintf("%s (%s)", name, formatSensorTime(at, true, ""))
+	}
+	return side(d.From, d.FromTime) + " to " + side(d.To, d.ToTime)
+}
+
+// sensorLabel returns "hostname (SID)", with the OID for sensors of
+// multi-org snapshots.
+func sensorLabel(s snapshot.SensorInfo, withOID bool) string {
+	if withOID && s.OID != "" {
+		return fmt.Sprintf("%s (%s, org %s)", s.Hostname, s.SID, s.OID)
+	}
+	return fmt.Sprintf("%s (%s)", s.Hostname, s.SID)
+}
+
+// changeDescriptions returns one line per changed attribute of a sensor.
+func changeDescriptions(c snapshot.Change) []string {
+	var lines []string
+	if c.Hostname != nil {
+		lines = append(lines, fmt.Sprintf("hostname: %s → %s", valueOrNone(c.Hostname.From), valueOrNone(c.Hostname.To)))
+	}
+	if c.InternalIP != nil {
+		lines = append(lines, fmt.Sprintf("internal IP: %s → %s", valueOrNone(c.InternalIP.From), valueOrNone(c.InternalIP.To)))
+	}
+	if c.ExternalIP != nil {
+		lines = append(lines, fmt.Sprintf("external IP: %s → %s", valueOrNone(c.ExternalIP.From), valueOrNone(c.ExternalIP.To)))
+	}
+	if c.Version != nil {
+		lines = append(lines, fmt.Sprintf("version: %s → %s (%s)", valueOrNone(c.Version.From), valueOrNone(c.Version.To), c.Version.Kind))
+	}
+	if c.Isolated != nil {
+		if c.Isolated.To {
+			lines = append(lines, "isolated from the network")
+		} else {
+			lines = append(lines, "rejoined the network")
+		}
+	}
+	if len(c.TagsAdded) > 0 {
+		lines = append(lines, "tags added: "+strings.Join(c.TagsAdded, ", "))
+	}
+	if len(c.TagsRemoved) > 0 {
+		lines = append(lines, "tags removed: "+strings.Join(c.TagsRemoved, ", "))
+	}
+	return lines
+}
+
+// diffSpansOrgs reports whether a diff covers several organizations, so
+// that sensors are shown with their OID.
+func diffSpansOrgs(d *snapshot.Diff) bool {
+	oids := map[string]bool{}
+	for _, s := range d.Added {
+		oids[s.OID] = true
+	}
+	for _, s := range d.Removed {
+		oids[s.OID] = true
+	}
+	for _, c := range d.Changed {
+		oids[c.Sensor.OID] = true
+	}
+	return len(oids) > 1
+}
+
+// writeDiffText prints a diff for the terminal.
+func writeDiffText(d *snapshot.Diff) {
+	withOID := diffSpansOrgs(d)
+	color.Green("\nChanges from %s:", diffTitle(d))
+	fmt.Printf("%d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+	if d.Empty() {
+		color.Blue("No changes")
+		return
+	}
+
+	if len(d.Added) > 0 {
+		color.Cyan("\nAdded (%d):", len(d.Added))
+		for _, s := range d.Added {
+			color.Green("  + %s [%s]", sensorLabel(s, withOID), s.Platform)
+		}
+	}
+	if len(d.Removed) > 0 {
+		color.Cyan("\nRemoved (%d):", len(d.Removed))
+		for _, s := range d.Removed {
+			color.Red("  - %s [%s]", sensorLabel(s, withOID), s.Platform)
+		}
+	}
+	if len(d.Changed) > 0 {
+		color.Cyan("\nChanged (%d):", len(d.Changed))
+		for _, c := range d.Changed {
+			color.Yellow("  ~ %s", sensorLabel(c.Sensor, withOID))
+			for _, line := range changeDescriptions(c) {
+				fmt.Printf("      %s\n", line)
+			}
+		}
+	}
+}
+
+// writeDiffMarkdown writes a diff as a markdown report, e.g. to attach
+// to a ticket.
+//
+// Parameters:
+//   - w: Destination of the report
+//   - d: The diff
+//
+// Returns:
+//   - error: Any error writing to w
+func writeDiffMarkdown(w io.Writer, d *snapshot.Diff) error {
+	withOID := diffSpansOrgs(d)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Sensor changes\n\nFrom %s.\n\n", mdEscape(diffTitle(d)))
+	sb.WriteString("| Added | Removed | Changed |\n|------:|--------:|--------:|\n")
+	fmt.Fprintf(&sb, "| %d | %d | %d |\n", len(d.Added), len(d.Removed), len(d.Changed))
+
+	sensorTable := func(title string, sensors []snapshot.SensorInfo) {
+		if len(sensors) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n## %s (%d)\n\n", title, len(sensors))
+		if withOID {
+			sb.WriteString("| OID | Hostname | SID | Platform |\n|-----|----------|-----|----------|\n")
+		} else {
+			sb.WriteString("| Hostname | SID | Platform |\n|----------|-----|----------|\n")
+		}
+		for _, s := range sensors {
+			if withOID {
+				fmt.Fprintf(&sb, "| %s ", mdEscape(s.OID))
+			}
+			fmt.Fprintf(&sb, "| %s | `%s` | %s |\n", mdEscape(s.Hostname), s.SID, mdEscape(s.Platform))
+		}
+	}
+	sensorTable("Added", d.Added)
+	sensorTable("Removed", d.Removed)
+
+	if len(d.Changed) > 0 {
+		fmt.Fprintf(&sb, "\n## Changed (%d)\n\n", len(d.Changed))
+		if withOID {
+			sb.WriteString("| OID | Hostname | SID | Changes |\n|-----|----------|-----|---------|\n")
+		} else {
+			sb.WriteString("| Hostname | SID | Changes |\n|----------|-----|---------|\n")
+		}
+		for _, c := range d.Changed {
+			if withOID {
+				fmt.Fprintf(&sb, "| %s ", mdEscape(c.Sensor.OID))
+			}
+			lines := changeDescriptions(c)
+			for i := range lines {
+				lines[i] = mdEscape(lines[i])
+			}
+			fmt.Fprintf(&sb, "| %s | `%s` | %s |\n", mdEscape(c.Sensor.Hostname), c.Sensor.SID, strings.Join(lines, "<br>"))
+		}
+	}
+	if d.Empty() {
+		sb.WriteString("\nNo changes.\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// mdEscape escapes the characters that would break a markdown table
+// cell or add formatting.
+func mdEscape(s string) string {
+	return strings.NewReplacer(`|`, `\|`, `*`, `\*`, `_`, `\_`, "`", "\\`", "\n", " ").Replace(s)
+}
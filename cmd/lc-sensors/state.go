@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/fanout"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// State change verification flags
+	verifyTimeout time.Duration
+	pollInterval  time.Duration
+)
+
+// sensorStateChange describes a command that switches an on/off sensor
+// state, such as network isolation or sealing, and verifies the change.
+type sensorStateChange struct {
+	// verb is the action, e.g. "isolate"
+	verb string
+	// done describes a sensor once changed, e.g. "isolated"
+	done string
+	// skipReason describes sensors that need no change, e.g. "already isolated"
+	skipReason string
+	// want is the requested state
+	want bool
+	// current reads the state from a sensor
+	current func(api.Sensor) bool
+	// apply requests the change for one sensor
+	apply func(ctx context.Context, client *api.Client, sensorID string) error
+}
+
+// verifyHelp describes the verification of a state change in the help
+// of the commands that make one.
+const verifyHelp = `After the requests are sent, each sensor is polled until it reports the new
+state or --verify-timeout passes. Sensors that never confirm are listed;
+offline sensors apply the change when they reconnect.`
+
+// addVerifyFlags registers --verify-timeout and --poll-interval on a
+// state change command.
+func addVerifyFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&verifyTimeout, "verify-timeout", 2*time.Minute, "How long to wait for sensors to confirm the change (0 skips verification)")
+	cmd.Flags().DurationVar(&pollInterval, "poll-interval", 5*time.Second, "Time between checks of the sensors' state")
+}
+
+// validateVerifyFlags checks the verification flags.
+func validateVerifyFlags() error {
+	if verifyTimeout < 0 || pollInterval <= 0 {
+		return fmt.Errorf("--verify-timeout must not be negative and --poll-interval must be positive")
+	}
+	return nil
+}
+
+// runStateChange selects sensors with the selection flags, asks for
+// confirmation, requests the change on every sensor not already in the
+// requested state and then verifies it.
+func runStateChange(cmd *cobra.Command, change sensorStateChange) {
+	ctx := cmd.Context()
+
+	// Print banner
+	fmt.Print(printBanner())
+
+	// Initialize API clients, one per organization
+	orgs, exitStatus := connectOrgs(cmd)
+
+	// Sensors already in the requested state are left alone
+	selected, failedOrgs := selectAndConfirm(ctx, orgs, exitStatus, func(sensor api.Sensor) bool {
+		return change.current(sensor) != change.want
+	}, change.skipReason, change.verb)
+
+	// Send the requests
+	color.Blue("\nSending %s requests...", change.verb)
+	var sentCount, failCount atomic.Int64
+	results := runOnSensors(ctx, selected, func(ctx context.Context, org *orgClient, sensor api.Sensor) error {
+		if err := change.apply(ctx, org.client, sensor.SID); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			printLocked(func() {
+				color.Red("%sFailed to %s sensor %s (%s): %v", org.prefix(), change.verb, sensor.Hostname, sensor.SID, err)
+			})
+			failCount.Add(1)
+			return err
+		}
+		printLocked(func() {
+			color.Green("%sSent %s request to sensor %s (%s)", org.prefix(), change.verb, sensor.Hostname, sensor.SID)
+		})
+		sentCount.Add(1)
+		return nil
+	})
+	skippedCount := countSkipped(ctx, results)
+	code := combineExitCodes(exitStatus, resultsExitCode(ctx, results))
+
+	// Wait for the sensors that accepted the request to report the change
+	var pending []int
+	for i, r := range results {
+		if r.Started && r.Err == nil {
+			pending = append(pending, i)
+		}
+	}
+	confirmed := 0
+	unconfirmed := pending
+	var lastState map[int]*api.Sensor
+	verified := verifyTimeout > 0 && ctx.Err() == nil && len(pending) > 0
+	if verified {
+		color.Blue("\nWaiting up to %s for %d sensors to confirm...", verifyTimeout, len(pending))
+		confirmed, unconfirmed, lastState = verifyStateChange(ctx, selected, pending, change)
+	}
+
+	// Print summary; only sensors seen in the new state count as confirmed
+	printFailedSensors(ctx, selected, results)
+	fmt.Println()
+	switch {
+	case verifyTimeout == 0:
+		color.Green("Sent %s requests to %d sensors", change.verb, sentCount.Load())
+	case !verified && len(pending) > 0:
+		color.Yellow("Sent %d %s requests, verification skipped", len(pending), change.verb)
+	case ctx.Err() != nil && len(unconfirmed) > 0:
+		color.Green("%d sensors confirmed %s", confirmed, change.done)
+		color.Yellow("Sent %d %s requests, verification interrupted", len(pending), change.verb)
+	default:
+		color.Green("%d sensors confirmed %s", confirmed, change.done)
+	}
+	if failCount.Load() > 0 {
+		color.Red("Failed to %s %d sensors", change.verb, failCount.Load())
+	}
+	if verifyTimeout > 0 && len(unconfirmed) > 0 {
+		if ctx.Err() != nil {
+			color.Yellow("\n%d sensors were not verified as %s:", len(unconfirmed), change.done)
+		} else {
+			color.Red("\n%d sensors did not confirm being %s within %s:", len(unconfirmed), change.done, verifyTimeout)
+		}
+		for _, i := range unconfirmed {
+			ts := selected[i]
+			reason := unconfirmedReason(lastState[i])
+			if lastState[i] == nil && ctx.Err() != nil {
+				reason = "not checked"
+			}
+			fmt.Printf("- %s%s (%s): %s\n", ts.org.prefix(), ts.sensor.Hostname, ts.sensor.SID, reason)
+		}
+		code = combineExitCodes(code, exitError)
+	}
+	printRetrySummary(orgsClients(orgs)...)
+	printCancelled(ctx, skippedCount, "sensors")
+	exitWithOrgSummary(ctx, orgs, selected, results, failedOrgs, code)
+}
+
+// verifyStateChange polls the given sensors every --poll-interval until
+// each reports the requested state, --verify-timeout passes or ctx is
+// cancelled.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation
+//   - sensors: The selected sensors
+//   - pending: Indexes into sensors of the sensors to verify
+//   - change: The requested change
+//
+// Returns:
+//   - int: Number of sensors seen in the requested state
+//   - []int: Indexes of the sensors that did not confirm
+//   - map[int]*api.Sensor: The last state read for each of them, if any
+func verifyStateChange(ctx context.Context, sensors []targetSensor, pending []int, change sensorStateChange) (int, []int, map[int]*api.Sensor) {
+	deadline := time.Now().Add(verifyTimeout)
+	confirmed := 0
+	lastState := map[int]*api.Sensor{}
+	pool := fanout.New(concurrency, 0)
+
+	for len(pending) > 0 {
+		// The change is applied asynchronously, so wait before each check
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			break
+		}
+		if wait > pollInterval {
+			wait = pollInterval
+		}
+		select {
+		case <-ctx.Done():
+			return confirmed, pending, lastState
+		case <-time.After(wait):
+		}
+
+		states := make([]*api.Sensor, len(pending))
+		pool.Run(ctx, len(pending), func(ctx context.Context, i int) error {
+			ts := sensors[pending[i]]
+			sensor, err := ts.org.client.GetSensor(ctx, ts.sensor.SID)
+			if err != nil {
+				slog.Debug("checking sensor state failed", slog.String("sid", ts.sensor.SID), slog.Any("error", err))
+				return err
+			}
+			states[i] = sensor
+			return nil
+		}, nil)
+
+		var still []int
+		for i, idx := range pending {
+			state := states[i]
+			if state != nil && change.current(*state) == change.want {
+				ts := sensors[idx]
+				printLocked(func() {
+					color.Green("%sSensor %s (%s) confirmed %s", ts.org.prefix(), ts.sensor.Hostname, ts.sensor.SID, change.done)
+				})
+				confirmed++
+				continue
+			}
+			if state != nil {
+				lastState[idx] = state
+			}
+			still = append(still, idx)
+		}
+		pending = still
+	}
+	return confirmed, pending, lastState
+}
+
+// unconfirmedReason describes why a sensor may not have confirmed a
+// state change, given its last known state.
+func unconfirmedReason(state *api.Sensor) string {
+	switch {
+	case state == nil:
+		return "state unknown"
+	case !state.IsOnline:
+		return "offline, the change applies when it reconnects"
+	default:
+		return "online but not yet changed"
+	}
+}
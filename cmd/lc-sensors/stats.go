@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/fanout"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Stats command flags
+	statsBy     []string
+	statsOutput string
+	statsChart  bool
+	statsTop    int
+)
+
+// statsDimension is a sensor attribute the fleet is broken down by.
+type statsDimension struct {
+	name  string
+	title string
+	// values returns the values of a sensor; tags give several
+	values func(api.Sensor) []string
+	// multi is set when a sensor may have several values, so that the
+	// groups cut by --top cannot be added up
+	multi bool
+}
+
+// statsDimensions are the available breakdowns, in output order.
+var statsDimensions = []statsDimension{
+	{"platform", "Platform", func(s api.Sensor) []string { return []string{s.GetPlatformString()} }, false},
+	{"arch", "Architecture", func(s api.Sensor) []string { return []string{s.GetArchitectureString()} }, false},
+	{"version", "Sensor Version", func(s api.Sensor) []string { return []string{valueOrUnknown(s.Version)} }, false},
+	{"online", "Status", func(s api.Sensor) []string { return []string{choose(s.IsOnline, "Online", "Offline")} }, false},
+	{"isolated", "Isolation", func(s api.Sensor) []string { return []string{choose(s.IsIsolated, "Isolated", "Not isolated")} }, false},
+	{"kernel", "Kernel", func(s api.Sensor) []string { return []string{choose(s.KernelAvailable, "Available", "Unavailable")} }, false},
+	{"tag", "Tag", func(s api.Sensor) []string {
+		if len(s.Tags) == 0 {
+			return []string{"(none)"}
+		}
+		return s.Tags
+	}, true},
+}
+
+// statsValue is the number of sensors with one value of a dimension.
+type statsValue struct {
+	Value   string  `json:"value"`
+	Sensors int     `json:"sensors"`
+	Percent float64 `json:"percent"`
+}
+
+// statsBreakdown is the distribution of one dimension.
+type statsBreakdown struct {
+	Dimension string       `json:"dimension"`
+	Values    []statsValue `json:"values"`
+
+	title string
+}
+
+// fleetStats is the output of the stats command.
+type fleetStats struct {
+	Total      int              `json:"total"`
+	Breakdowns []statsBreakdown `json:"breakdowns"`
+}
+
+// newStatsCmd returns the `stats` command, which breaks the fleet down
+// by platform, architecture, version, status and tag.
+func newStatsCmd() *cobra.Command {
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show fleet statistics",
+		Long: `Count sensors by platform, architecture, sensor version, online status,
+isolation, kernel availability and tag, with the percentage of the fleet
+in each group. Percentages of tags can add up to more than 100% as a
+sensor may have several tags.
+
+The usual filters apply, and with --orgs or --all-profiles the counts
+cover every organization.
+
+Example:
+  # Every breakdown, with bar charts
+  lc-sensors stats --chart
+
+  # Versions of the Windows sensors, as CSV for a dashboard
+  lc-sensors stats --by version --filter-platform windows -f csv
+
+  # The 10 most used tags
+  lc-sensors stats --by tag --top 10`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireCredentials(); err != nil {
+				return err
+			}
+			for _, name := range statsBy {
+				if findStatsDimension(name) == nil {
+					return fmt.Errorf("--by must be one or more of: %s", strings.Join(statsDimensionNames(), ", "))
+				}
+			}
+			if statsTop < 0 {
+				return fmt.Errorf("--top must not be negative")
+			}
+			switch statsOutput {
+			case "text", "json", "csv":
+			default:
+				return fmt.Errorf("--output must be one of: text, json, csv")
+			}
+			return prepareFilters()
+		},
+		Run: runStats,
+	}
+
+	statsCmd.Flags().StringSliceVar(&statsBy, "by", nil, "Breakdowns to show (comma-separated): "+strings.Join(statsDimensionNames(), ", ")+" (default all)")
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "f", "text", "Output format (text/json/csv)")
+	statsCmd.Flags().BoolVar(&statsChart, "chart", false, "Draw bar charts instead of tables")
+	statsCmd.Flags().IntVar(&statsTop, "top", 0, "Show only the N largest groups of each breakdown, adding up the rest as (other) except for tags; 0 shows all")
+	addSelectionFlags(statsCmd)
+	addTimeFilterFlags(statsCmd)
+	addOrgFlags(statsCmd)
+	return statsCmd
+}
+
+func runStats(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	// Keep machine-readable output clean
+	if statsOutput == "text" {
+		fmt.Print(printBanner())
+	}
+
+	// Initialize API clients, one per organization
+	orgs, exitStatus := connectOrgs(cmd)
+
+	dims := statsDimensions
+	if len(statsBy) > 0 {
+		dims = nil
+		for _, name := range statsBy {
+			if dim := findStatsDimension(name); !hasDimension(dims, dim.name) {
+				dims = append(dims, *dim)
+			}
+		}
+	}
+	opts := &api.ListOptions{
+		WithTags: needTags() || hasDimension(dims, "tag"),
+	}
+
+	// Count the sensors of every organization as they are listed
+	var mu sync.Mutex
+	total := 0
+	counts := make([]map[string]int, len(dims))
+	for i := range counts {
+		counts[i] = map[string]int{}
+	}
+	orgTotals := make([]orgCounts, len(orgs))
+	pool := fanout.New(orgConcurrency, 0)
+	results := pool.Run(ctx, len(orgs), func(ctx context.Context, i int) error {
+		return orgs[i].client.ForEachSensor(ctx, opts, func(sensor api.Sensor) error {
+			if !sensorMatchesFilters(sensor) {
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			total++
+			orgTotals[i].selected++
+			for d, dim := range dims {
+				for _, v := range dim.values(sensor) {
+					counts[d][v]++
+				}
+			}
+			return nil
+		})
+	}, nil)
+
+	if !multiOrg() && results[0].Err != nil {
+		if ctx.Err() != nil {
+			printCancelled(ctx, 0, "")
+			exitIfCancelled(ctx)
+		}
+		fatal("Failed to retrieve sensors", results[0].Err)
+	}
+
+	stats := fleetStats{Total: total}
+	for d, dim := range dims {
+		stats.Breakdowns = append(stats.Breakdowns, statsBreakdown{
+			Dimension: dim.name,
+			Values:    rankStatsValues(counts[d], total, statsTop, dim.multi),
+			title:     dim.title,
+		})
+	}
+	if err := writeStats(os.Stdout, statsOutput, stats); err != nil {
+		fatal("Failed to write output", err)
+	}
+
+	if multiOrg() {
+		// Keep machine-readable output clean by sending the summary to stderr
+		for i, r := range results {
+			orgTotals[i].err = r.Err
+		}
+		summaryOut := io.Writer(os.Stdout)
+		if statsOutput != "text" {
+			summaryOut = os.Stderr
+		}
+		printOrgSummary(summaryOut, orgs, orgTotals, false)
+	}
+	printCancelled(ctx, 0, "")
+	exitIfCancelled(ctx)
+	if code := combineExitCodes(exitStatus, resultsExitCode(ctx, results)); code != exitOK {
+		os.Exit(code)
+	}
+}
+
+// rankStatsValues sorts the counts of a dimension by size, largest
+// first, and computes their share of total. With top set, the groups
+// after the first top are added up as "(other)", or dropped if a sensor
+// can be in several groups.
+func rankStatsValues(counts map[string]int, total, top int, multi bool) []statsValue {
+	values := make([]statsValue, 0, len(counts))
+	for v, n := range counts {
+		values = append(values, statsValue{Value: v, Sensors: n})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Sensors != values[j].Sensors {
+			return values[i].Sensors > values[j].Sensors
+		}
+		return values[i].Value < values[j].Value
+	})
+
+	if top > 0 && len(values) > top {
+		other := statsValue{Value: "(other)"}
+		for _, v := range values[top:] {
+			other.Sensors += v.Sensors
+		}
+		values = values[:top]
+		if !multi {
+			values = append(values, other)
+		}
+	}
+	for i := range values {
+		if total > 0 {
+			values[i].Percent = float64(int(1000*float64(values[i].Sensors)/float64(total)+0.5)) / 10
+		}
+	}
+	return values
+}
+
+// writeStats writes the statistics in the given format.
+//
+// Parameters:
+//   - w: Destination of the output
+//   - format: "text", "json" or "csv"
+//   - stats: The statistics to write
+//
+// Returns:
+//   - error: Any error writing the output
+func writeStats(w io.Writer, format string, stats fleetStats) error {
+	switch format {
+	case "json":
+		for i := range stats.Breakdowns {
+			if stats.Breakdowns[i].Values == nil {
+				stats.Breakdowns[i].Values = []statsValue{}
+			}
+		}
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"Dimension", "Value", "Sensors", "Percent"})
+		for _, b := range stats.Breakdowns {
+			for _, v := range b.Values {
+				cw.Write([]string{b.Dimension, v.Value, fmt.Sprint(v.Sensors), fmt.Sprintf("%.1f", v.Percent)})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	color.Green("\nFleet statistics for %d sensors:", stats.Total)
+	for _, b := range stats.Breakdowns {
+		fmt.Fprintln(w)
+		color.Cyan("%s", b.title)
+		if statsChart {
+			writeStatsChart(w, b.Values)
+			continue
+		}
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{b.title, "Sensors", "Percent"})
+		table.SetBorder(false)
+		table.SetAutoWrapText(false)
+		for _, v := range b.Values {
+			table.Append([]string{v.Value, fmt.Sprint(v.Sensors), fmt.Sprintf("%.1f%%", v.Percent)})
+		}
+		table.Render()
+	}
+	return nil
+}
+
+// statsBarWidth is the length of a bar for 100% of the sensors.
+const statsBarWidth = 40
+
+// writeStatsChart draws one horizontal bar per value, scaled to the
+// share of the fleet.
+func writeStatsChart(w io.Writer, values []statsValue) {
+	width := 0
+	for _, v := range values {
+		if len(v.Value) > width {
+			width = len(v.Value)
+		}
+	}
+	for _, v := range values {
+		bar := int(v.Percent/100*statsBarWidth + 0.5)
+		if bar == 0 && v.Sensors > 0 {
+			bar = 1
+		}
+		fmt.Fprintf(w, "  %-*s %s %d (%.1f%%)\n", width, v.Value, color.BlueString(strings.Repeat("█", bar)), v.Sensors, v.Percent)
+	}
+}
+
+// findStatsDimension returns the dimension with the given name, or nil.
+func findStatsDimension(name string) *statsDimension {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "plat":
+		name = "platform"
+	case "architecture":
+		name = "arch"
+	case "tags":
+		name = "tag"
+	}
+	for i := range statsDimensions {
+		if statsDimensions[i].name == name {
+			return &statsDimensions[i]
+		}
+	}
+	return nil
+}
+
+// hasDimension reports whether dims includes the named dimension.
+func hasDimension(dims []statsDimension, name string) bool {
+	for _, d := range dims {
+		if d.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// statsDimensionNames returns the names accepted by --by.
+func statsDimensionNames() []string {
+	names := make([]string, len(statsDimensions))
+	for i, d := range statsDimensions {
+		names[i] = d.name
+	}
+	return names
+}
+
+// valueOrUnknown returns s, or "(unknown)" if it is empty.
+func valueOrUnknown(s string) string {
+	if s == "" {
+		return "(unknown)"
+	}
+	return s
+}
+
+// choose returns yes if cond is true and no otherwise.
+func choose(cond bool, yes, no string) string {
+	if cond {
+		return yes
+	}
+	return no
+}
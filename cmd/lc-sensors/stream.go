@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"LC_utils/internal/api"
+)
+
+// sensorWriter writes sensors in one of the list output formats as they
+// are retrieved, so large organizations are not held in memory. In
+// multi-org runs every format gains the sensor's profile and OID.
+type sensorWriter interface {
+	// Write outputs one sensor of the given organization
+	Write(org *orgClient, sensor api.Sensor) error
+	// Close completes the output, e.g. the closing bracket of a JSON array
+	Close() error
+}
+
+// newSensorWriter returns a writer for the given output format. The text
+// format needs every sensor to size its table, so it buffers until Close.
+func newSensorWriter(format string, w io.Writer) sensorWriter {
+	switch format {
+	case "json":
+		return &jsonSensorWriter{w: w}
+	case "csv":
+		cw := csv.NewWriter(w)
+		// New columns are appended so that scripts reading columns by
+		// position keep working
+		header := []string{"SID", "Hostname", "Platform", "Architecture", "Last Seen", "Enrollment Time", "External IP", "Internal IP", "Online", "Tags", "Sealed", "External Platform"}
+		if multiOrg() {
+			header = append([]string{"Profile", "OID"}, header...)
+		}
+		cw.Write(header)
+		cw.Flush()
+		return &csvSensorWriter{w: cw}
+	default:
+		return &textSensorWriter{}
+	}
+}
+
+// jsonSensorWriter streams sensors as an indented JSON array.
+type jsonSensorWriter struct {
+	w     io.Writer
+	count int
+}
+
+// orgSensorJSON adds the profile to a sensor's JSON in multi-org runs;
+// the OID is already part of the sensor.
+type orgSensorJSON struct {
+	Profile string `json:"profile,omitempty"`
+	api.Sensor
+}
+
+// MarshalJSON puts the profile first in the sensor's own encoding, which
+// the embedded api.Sensor would otherwise replace entirely.
+func (o orgSensorJSON) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(o.Sensor)
+	if err != nil || o.Profile == "" {
+		return data, err
+	}
+	profile, err := json.Marshal(o.Profile)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte(`{"profile":`), profile...)
+	if len(data) > 2 {
+		out = append(out, ',')
+	}
+	return append(out, data[1:]...), nil
+}
+
+func (j *jsonSensorWriter) Write(org *orgClient, sensor api.Sensor) error {
+	var v interface{} = sensor
+	if multiOrg() {
+		if sensor.OID == "" {
+			sensor.OID = org.target.OID
+		}
+		v = orgSensorJSON{Profile: org.target.Profile, Sensor: sensor}
+	}
+	data, err := json.MarshalIndent(v, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format JSON: %w", err)
+	}
+	sep := ",\n  "
+	if j.count == 0 {
+		sep = "[\n  "
+	}
+	j.count++
+	_, err = fmt.Fprintf(j.w, "%s%s", sep, data)
+	return err
+}
+
+func (j *jsonSensorWriter) Close() error {
+	if j.count == 0 {
+		_, err := fmt.Fprintln(j.w, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(j.w, "\n]")
+	return err
+}
+
+// csvSensorWriter streams sensors as CSV rows, flushing after each row.
+type csvSensorWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvSensorWriter) Write(org *orgClient, sensor api.Sensor) error {
+	var row []string
+	if multiOrg() {
+		row = []string{org.target.Profile, org.target.OID}
+	}
+	c.w.Write(append(row,
+		sensor.SID,
+		sensor.Hostname,
+		sensor.GetPlatformString(),
+		sensor.GetArchitectureString(),
+		sensor.GetLastSeenString(),
+		sensor.GetEnrollmentTimeString(),
+		sensor.ExternalIP,
+		sensor.InternalIP,
+		fmt.Sprintf("%v", sensor.IsOnline),
+		strings.Join(sensor.Tags, ", "),
+		fmt.Sprintf("%v", sensor.IsSealed),
+		sensor.GetExternalPlatformString(),
+	))
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvSensorWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// textSensorWriter collects sensors and renders the table on Close.
+type textSensorWriter struct {
+	sensors []targetSensor
+}
+
+func (t *textSensorWriter) Write(org *orgClient, sensor api.Sensor) error {
+	t.sensors = append(t.sensors, targetSensor{org: org, sensor: sensor})
+	return nil
+}
+
+func (t *textSensorWriter) Close() error {
+	outputText(t.sensors)
+	return nil
+}
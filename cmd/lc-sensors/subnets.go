@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"sync"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/fanout"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Subnets command flags
+	subnetPrefix4 int
+	subnetPrefix6 int
+	subnetBy      string
+	subnetOutput  string
+)
+
+// subnetRow is the number of sensors in one subnet of one organization.
+type subnetRow struct {
+	Profile string `json:"profile,omitempty"`
+	OID     string `json:"oid,omitempty"`
+	Subnet  string `json:"subnet"`
+	Sensors int    `json:"sensors"`
+	Online  int    `json:"online"`
+	Offline int    `json:"offline"`
+
+	org    int
+	prefix netip.Prefix
+}
+
+// newSubnetsCmd returns the `subnets` command, which groups sensors by
+// subnet.
+func newSubnetsCmd() *cobra.Command {
+	subnetsCmd := &cobra.Command{
+		Use:   "subnets",
+		Short: "Count sensors per subnet",
+		Long: `Group sensors by the subnet of their internal (or external) IP address and
+show how many are online in each. IPv4 addresses are grouped by --prefix
+(default /24) and IPv6 addresses by --prefix6 (default /64). Sensors
+without an address are counted under "(none)".
+
+The usual filters apply, so a subnet can be drilled into with --int-cidr.
+
+Example:
+  # Sensors per /24 in the 10.0.0.0/8 range
+  lc-sensors subnets --int-cidr 10.0.0.0/8
+
+  # Sensors per /16 of their external address, as CSV
+  lc-sensors subnets --by ext --prefix 16 -f csv`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireCredentials(); err != nil {
+				return err
+			}
+			if subnetPrefix4 < 0 || subnetPrefix4 > 32 {
+				return fmt.Errorf("--prefix must be between 0 and 32")
+			}
+			if subnetPrefix6 < 0 || subnetPrefix6 > 128 {
+				return fmt.Errorf("--prefix6 must be between 0 and 128")
+			}
+			if subnetBy != "int" && subnetBy != "ext" {
+				return fmt.Errorf("--by must be int or ext")
+			}
+			switch subnetOutput {
+			case "text", "json", "csv":
+			default:
+				return fmt.Errorf("--output must be one of: text, json, csv")
+			}
+			return prepareFilters()
+		},
+		Run: runSubnets,
+	}
+
+	subnetsCmd.Flags().IntVar(&subnetPrefix4, "prefix", 24, "Prefix length of IPv4 subnets")
+	subnetsCmd.Flags().IntVar(&subnetPrefix6, "prefix6", 64, "Prefix length of IPv6 subnets")
+	subnetsCmd.Flags().StringVar(&subnetBy, "by", "int", "Address to group by (int, ext)")
+	subnetsCmd.Flags().StringVarP(&subnetOutput, "output", "f", "text", "Output format (text/json/csv)")
+	addSelectionFlags(subnetsCmd)
+	addTimeFilterFlags(subnetsCmd)
+	addOrgFlags(subnetsCmd)
+	return subnetsCmd
+}
+
+func runSubnets(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	// Keep machine-readable output clean
+	if subnetOutput == "text" {
+		fmt.Print(printBanner())
+	}
+
+	// Initialize API clients, one per organization
+	orgs, exitStatus := connectOrgs(cmd)
+
+	// Count the sensors of every organization as they are listed
+	opts := &api.ListOptions{
+		WithTags: needTags(),
+	}
+	var mu sync.Mutex
+	rows := map[[2]string]*subnetRow{}
+	counts := make([]orgCounts, len(orgs))
+	pool := fanout.New(orgConcurrency, 0)
+	results := pool.Run(ctx, len(orgs), func(ctx context.Context, i int) error {
+		return orgs[i].client.ForEachSensor(ctx, opts, func(sensor api.Sensor) error {
+			if !sensorMatchesFilters(sensor) {
+				return nil
+			}
+			ip := sensor.InternalIP
+			if subnetBy == "ext" {
+				ip = sensor.ExternalIP
+			}
+			prefix, ok := subnetOf(ip)
+
+			mu.Lock()
+			defer mu.Unlock()
+			counts[i].selected++
+			key := [2]string{orgs[i].target.Label, "(none)"}
+			if ok {
+				key[1] = prefix.String()
+			}
+			row := rows[key]
+			if row == nil {
+				row = &subnetRow{Subnet: key[1], org: i, prefix: prefix}
+				if multiOrg() {
+					row.Profile = orgs[i].target.Profile
+					row.OID = orgs[i].target.OID
+				}
+				rows[key] = row
+			}
+			row.Sensors++
+			if sensor.IsOnline {
+				row.Online++
+			} else {
+				row.Offline++
+			}
+			return nil
+		})
+	}, nil)
+
+	if !multiOrg() && results[0].Err != nil {
+		if ctx.Err() != nil {
+			printCancelled(ctx, 0, "")
+			exitIfCancelled(ctx)
+		}
+		fatal("Failed to retrieve sensors", results[0].Err)
+	}
+
+	// Order by organization, then subnet, with sensors without an address last
+	sorted := make([]*subnetRow, 0, len(rows))
+	for _, row := range rows {
+		sorted = append(sorted, row)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.org != b.org {
+			return a.org < b.org
+		}
+		if a.prefix.IsValid() != b.prefix.IsValid() {
+			return a.prefix.IsValid()
+		}
+		if a.prefix.Addr() != b.prefix.Addr() {
+			return a.prefix.Addr().Less(b.prefix.Addr())
+		}
+		return a.prefix.Bits() < b.prefix.Bits()
+	})
+
+	if err := writeSubnets(os.Stdout, subnetOutput, orgs, sorted); err != nil {
+		fatal("Failed to write output", err)
+	}
+
+	if multiOrg() {
+		// Keep machine-readable output clean by sending the summary to stderr
+		for i, r := range results {
+			counts[i].err = r.Err
+		}
+		summaryOut := io.Writer(os.Stdout)
+		if subnetOutput != "text" {
+			summaryOut = os.Stderr
+		}
+		printOrgSummary(summaryOut, orgs, counts, false)
+	}
+	printCancelled(ctx, 0, "")
+	exitIfCancelled(ctx)
+	if code := combineExitCodes(exitStatus, resultsExitCode(ctx, results)); code != exitOK {
+		os.Exit(code)
+	}
+}
+
+// subnetOf returns the subnet of an address using --prefix or --prefix6.
+// It reports false for empty or invalid addresses.
+func subnetOf(ip string) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+	bits := subnetPrefix4
+	if addr.Is6() {
+		bits = subnetPrefix6
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+// writeSubnets writes the subnet counts in the given format.
+//
+// Parameters:
+//   - w: Destination of the output
+//   - format: "text", "json" or "csv"
+//   - orgs: The organizations, labelling text rows in multi-org runs
+//   - rows: The subnets, in output order
+//
+// Returns:
+//   - error: Any error writing the output
+func writeSubnets(w io.Writer, format string, orgs []*orgClient, rows []*subnetRow) error {
+	switch format {
+	case "json":
+		if rows == nil {
+			rows = []*subnetRow{}
+		}
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := []string{"Subnet", "Sensors", "Online", "Offline"}
+		if multiOrg() {
+			header = append([]string{"Profile", "OID"}, header...)
+		}
+		cw.Write(header)
+		for _, r := range rows {
+			var row []string
+			if multiOrg() {
+				row = []string{r.Profile, r.OID}
+			}
+			cw.Write(append(row, r.Subnet, fmt.Sprint(r.Sensors), fmt.Sprint(r.Online), fmt.Sprint(r.Offline)))
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	total, online := 0, 0
+	for _, r := range rows {
+		total += r.Sensors
+		online += r.Online
+	}
+	color.Green("\nFound %d sensors in %d subnets (%d online):", total, len(rows), online)
+
+	table := tablewriter.NewWriter(w)
+	header := []string{"Subnet", "Sensors", "Online", "Offline", "Online %"}
+	if multiOrg() {
+		header = append([]string{"Org"}, header...)
+	}
+	table.SetHeader(header)
+	table.SetBorder(false)
+	for _, r := range rows {
+		var row []string
+		if multiOrg() {
+			row = []string{orgs[r.org].target.Label}
+		}
+		table.Append(append(row,
+			r.Subnet,
+			fmt.Sprint(r.Sensors),
+			fmt.Sprint(r.Online),
+			fmt.Sprint(r.Offline),
+			fmt.Sprintf("%.0f%%", 100*float64(r.Online)/float64(r.Sensors)),
+		))
+	}
+	table.Render()
+	return nil
+}
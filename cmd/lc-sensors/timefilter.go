@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"LC_utils/internal/api"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Time filter flags
+	lastSeenBefore string
+	lastSeenWithin string
+	enrolledSince  string
+
+	// Bounds parsed from the time filter flags; zero when unset
+	lastSeenBeforeTime time.Time
+	lastSeenAfterTime  time.Time
+	enrolledSinceTime  time.Time
+)
+
+// addTimeFilterFlags registers the filters on the sensors' last-seen and
+// enrollment times.
+func addTimeFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&lastSeenBefore, "last-seen-before", "", "Only sensors last seen before a time or age, e.g. 2026-01-01 or 7d")
+	cmd.Flags().StringVar(&lastSeenWithin, "last-seen-within", "", "Only sensors seen within a duration, e.g. 24h or 7d")
+	cmd.Flags().StringVar(&enrolledSince, "enrolled-since", "", "Only sensors enrolled since a time or age, e.g. 2026-01-01 or 30d")
+}
+
+// parseTimeFilters parses the time filter flags. Dates without a zone
+// are in the --tz time zone.
+func parseTimeFilters() error {
+	now := time.Now()
+	var err error
+	if lastSeenBefore != "" {
+		if lastSeenBeforeTime, err = parseTimeSpec(lastSeenBefore, now); err != nil {
+			return fmt.Errorf("--last-seen-before: %w", err)
+		}
+	}
+	if lastSeenWithin != "" {
+		d, err := parseAge(lastSeenWithin)
+		if err != nil {
+			return fmt.Errorf("--last-seen-within: %w", err)
+		}
+		lastSeenAfterTime = now.Add(-d)
+	}
+	if enrolledSince != "" {
+		if enrolledSinceTime, err = parseTimeSpec(enrolledSince, now); err != nil {
+			return fmt.Errorf("--enrolled-since: %w", err)
+		}
+	}
+	if !lastSeenBeforeTime.IsZero() && !lastSeenAfterTime.IsZero() && !lastSeenAfterTime.Before(lastSeenBeforeTime) {
+		return fmt.Errorf("--last-seen-within and --last-seen-before select no time range")
+	}
+	return nil
+}
+
+// sensorMatchesTimeFilters reports whether a sensor passes the time
+// filters. A sensor whose time is unknown never passes a filter on it.
+func sensorMatchesTimeFilters(sensor api.Sensor) bool {
+	if !lastSeenBeforeTime.IsZero() || !lastSeenAfterTime.IsZero() {
+		lastSeen, ok := sensor.LastSeenTime()
+		if !ok {
+			return false
+		}
+		if !lastSeenBeforeTime.IsZero() && !lastSeen.Before(lastSeenBeforeTime) {
+			return false
+		}
+		if !lastSeenAfterTime.IsZero() && lastSeen.Before(lastSeenAfterTime) {
+			return false
+		}
+	}
+	if !enrolledSinceTime.IsZero() {
+		enrolled, ok := sensor.EnrolledTime()
+		if !ok || enrolled.Before(enrolledSinceTime) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/fanout"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Watch command flags
+	watchInterval  time.Duration
+	watchBatchSize int
+	watchNDJSON    string
+)
+
+// watchEvent is an online status transition, as written to --ndjson.
+type watchEvent struct {
+	Time     time.Time `json:"time"`
+	Profile  string    `json:"profile,omitempty"`
+	OID      string    `json:"oid"`
+	SID      string    `json:"sid"`
+	Hostname string    `json:"hostname"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+}
+
+// watchBatch is one online status request: up to --batch-size sensors
+// of one organization.
+type watchBatch struct {
+	org     *orgClient
+	sensors []int
+}
+
+// newWatchCmd returns the `watch` command, which reports sensors going
+// online and offline.
+func newWatchCmd() *cobra.Command {
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch sensors going online and offline",
+		Long: `Check the online status of the selected sensors every --interval and print
+each ONLINE → OFFLINE and OFFLINE → ONLINE transition with the time it
+was seen. The status of up to --batch-size sensors is read with each
+request, so large fleets take few requests.
+
+Sensors are selected once, when the command starts, with the usual
+filters. The command runs until interrupted with Ctrl-C or until
+--timeout, which both end it normally.
+
+Example:
+  # Watch the production Windows servers
+  lc-sensors watch --filter-platform windows --filter-tag prod
+
+  # Record the transitions of every organization for a day
+  lc-sensors watch --all-profiles --interval 1m --timeout 24h --ndjson transitions.ndjson`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireCredentials(); err != nil {
+				return err
+			}
+			if watchInterval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			if watchBatchSize <= 0 {
+				return fmt.Errorf("--batch-size must be positive")
+			}
+			return prepareFilters()
+		},
+		Run: runWatch,
+	}
+
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 30*time.Second, "Time between status checks")
+	watchCmd.Flags().IntVar(&watchBatchSize, "batch-size", 500, "Number of sensors checked per request")
+	watchCmd.Flags().StringVar(&watchNDJSON, "ndjson", "", "Append transitions as JSON lines to this file (- for stdout)")
+	addSelectionFlags(watchCmd)
+	addTimeFilterFlags(watchCmd)
+	addFanOutFlags(watchCmd)
+	addOrgFlags(watchCmd)
+	return watchCmd
+}
+
+func runWatch(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+
+	// With NDJSON on stdout, keep it clean by sending the rest to stderr
+	out := io.Writer(os.Stdout)
+	var events *json.Encoder
+	switch watchNDJSON {
+	case "":
+	case "-":
+		out = os.Stderr
+		events = json.NewEncoder(os.Stdout)
+	default:
+		f, err := os.OpenFile(watchNDJSON, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			fatal("Failed to open NDJSON file", err)
+		}
+		defer f.Close()
+		events = json.NewEncoder(f)
+	}
+	if watchNDJSON != "-" {
+		fmt.Print(printBanner())
+	}
+
+	// Initialize API clients, one per organization
+	orgs, exitStatus := connectOrgs(cmd)
+
+	opts := &api.ListOptions{WithTags: needTags()}
+	selected, failedOrgs := selectSensors(ctx, orgs, opts, sensorMatchesFilters)
+	if len(selected) == 0 {
+		color.New(color.FgYellow).Fprintln(out, "No sensors match the filters")
+		os.Exit(failedOrgsExitCode(exitStatus, failedOrgs))
+	}
+
+	// The listing gives the starting state
+	online := make([]bool, len(selected))
+	onlineCount := 0
+	for i, ts := range selected {
+		online[i] = ts.sensor.IsOnline
+		if online[i] {
+			onlineCount++
+		}
+	}
+	batches := watchBatches(orgs, selected, watchBatchSize)
+	color.New(color.FgBlue).Fprintf(out, "\nWatching %d sensors (%d online, %d offline) every %s in %d requests, press Ctrl-C to stop\n\n",
+		len(selected), onlineCount, len(selected)-onlineCount, watchInterval, len(batches))
+
+	start := time.Now()
+	wentOffline, cameOnline := 0, 0
+	pool := fanout.New(concurrency, 0)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		statuses := make([]*api.OnlineStatusResponse, len(batches))
+		results := pool.Run(ctx, len(batches), func(ctx context.Context, i int) error {
+			b := batches[i]
+			sids := make([]string, len(b.sensors))
+			for j, idx := range b.sensors {
+				sids[j] = selected[idx].sensor.SID
+			}
+			resp, err := b.org.client.GetOnlineStatus(ctx, sids)
+			if err != nil {
+				return err
+			}
+			statuses[i] = resp
+			return nil
+		}, nil)
+		if ctx.Err() != nil {
+			break
+		}
+
+		now := time.Now()
+		if code := watchPollFailure(out, batches, results); code != exitOK {
+			os.Exit(combineExitCodes(exitStatus, code))
+		}
+		for i, b := range batches {
+			if statuses[i] == nil {
+				continue
+			}
+			for _, idx := range b.sensors {
+				ts := selected[idx]
+				// Sensors missing from the response were deleted or are
+				// unknown; keep their last state
+				isOnline, ok := statuses[i].Online[ts.sensor.SID]
+				if !ok || isOnline == online[idx] {
+					continue
+				}
+				online[idx] = isOnline
+				if isOnline {
+					cameOnline++
+				} else {
+					wentOffline++
+				}
+				event := watchEvent{
+					Time:     now.UTC(),
+					Profile:  ts.org.target.Profile,
+					OID:      ts.org.target.OID,
+					SID:      ts.sensor.SID,
+					Hostname: ts.sensor.Hostname,
+					From:     onlineWord(!isOnline),
+					To:       onlineWord(isOnline),
+				}
+				printWatchEvent(out, ts.org, event)
+				if events != nil {
+					if err := events.Encode(event); err != nil {
+						fatal("Failed to write NDJSON", err)
+					}
+				}
+			}
+		}
+	}
+
+	// Interruption and --timeout are the normal ways to stop watching
+	color.New(color.FgGreen).Fprintf(out, "\nWatched %d sensors for %s: %d went offline, %d came online\n",
+		len(selected), formatAge(time.Since(start)), wentOffline, cameOnline)
+	printRetrySummary(orgsClients(orgs)...)
+	if code := failedOrgsExitCode(exitStatus, failedOrgs); code != exitOK {
+		os.Exit(code)
+	}
+}
+
+// watchBatches splits the selected sensors into requests of at most size
+// sensors, each for a single organization.
+//
+// Parameters:
+//   - orgs: The organizations
+//   - selected: The selected sensors
+//   - size: The maximum number of sensors per request
+//
+// Returns:
+//   - []watchBatch: The requests, in organization order
+func watchBatches(orgs []*orgClient, selected []targetSensor, size int) []watchBatch {
+	var batches []watchBatch
+	for _, org := range orgs {
+		first := len(batches)
+		for i, ts := range selected {
+			if ts.org != org {
+				continue
+			}
+			if len(batches) == first || len(batches[len(batches)-1].sensors) == size {
+				batches = append(batches, watchBatch{org: org})
+			}
+			last := &batches[len(batches)-1]
+			last.sensors = append(last.sensors, i)
+		}
+	}
+	return batches
+}
+
+// watchPollFailure reports the requests of a status check that failed.
+// Failures are expected now and then and the next check retries them,
+// unless every request was refused for a reason retrying cannot fix.
+//
+// Parameters:
+//   - out: Destination of the messages
+//   - batches: The requests
+//   - results: Their results
+//
+// Returns:
+//   - int: The exit code to stop with, or exitOK to keep watching
+func watchPollFailure(out io.Writer, batches []watchBatch, results []fanout.Result) int {
+	failed := map[*orgClient]int{}
+	var orgErrs []*orgClient
+	code, permanent := exitOK, 0
+	errs := map[*orgClient]error{}
+	for i, r := range results {
+		if r.Err == nil {
+			continue
+		}
+		org := batches[i].org
+		if _, ok := failed[org]; !ok {
+			orgErrs = append(orgErrs, org)
+			errs[org] = r.Err
+		}
+		failed[org] += len(batches[i].sensors)
+		slog.Debug("online status check failed", slog.String("oid", org.target.OID), slog.Any("error", r.Err))
+		switch c := exitCode(r.Err); c {
+		case exitAuth, exitForbidden, exitNotFound:
+			permanent++
+			code = combineExitCodes(code, c)
+		}
+	}
+
+	for _, org := range orgErrs {
+		color.New(color.FgRed).Fprintf(out, "%s %sFailed to check %d sensors: %v\n",
+			time.Now().In(displayLocation).Format("2006-01-02 15:04:05 MST"), org.prefix(), failed[org], errs[org])
+	}
+	if permanent == len(results) && permanent > 0 {
+		if hint := errorGuidance(errs[orgErrs[0]]); hint != "" {
+			color.New(color.FgYellow).Fprintln(out, hint)
+		}
+		return code
+	}
+	return exitOK
+}
+
+// printWatchEvent prints a transition as a timestamped line.
+func printWatchEvent(out io.Writer, org *orgClient, event watchEvent) {
+	c := color.New(color.FgGreen)
+	if event.To == "offline" {
+		c = color.New(color.FgRed)
+	}
+	c.Fprintf(out, "%s %s%s (%s) %s → %s\n",
+		event.Time.In(displayLocation).Format("2006-01-02 15:04:05 MST"), org.prefix(), event.Hostname, event.SID,
+		strings.ToUpper(event.From), strings.ToUpper(event.To))
+}
+
+// onlineWord returns "online" or "offline".
+func onlineWord(online bool) string {
+	return choose(online, "online", "offline")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML output is produced from a value's JSON encoding, so it uses the
+// same field names and omits the same empty fields as -f json. Field
+// order is preserved.
+
+// writeYAML writes v as a YAML document.
+//
+// Parameters:
+//   - w: Destination of the document
+//   - v: Any value that can be encoded as JSON
+//
+// Returns:
+//   - error: Any error encoding v or writing to w
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// JSON is valid YAML, so it parses into a document that keeps the
+	// key order; it only needs to be switched to block style
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to convert to YAML: %w", err)
+	}
+	clearYAMLStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// clearYAMLStyle resets the flow style and quotes of the JSON input, so
+// that the encoder uses block style and quotes only the strings that
+// need it.
+func clearYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		clearYAMLStyle(child)
+	}
+}
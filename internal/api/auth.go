@@ -24,7 +24,7 @@ import (
 // Returns:
 //   - error: An error if authentication fails, nil otherwise
 func ensureAuthenticated(creds *auth.Credentials) error {
-	if creds.GetAPIKey() == "" {
+	if creds.GetAPIKey() == "" && !creds.PreIssued() {
 		return fmt.Errorf("API key is empty")
 	}
 	return nil
@@ -0,0 +1,336 @@
+// Package api provides the shared API client for LimaCharlie.
+// This file implements client-related functionality including:
+// - A connection-pooled HTTP transport shared by every API call
+// - Configurable API and JWT endpoints
+// - Request construction with authentication and user agent headers
+// - Debug logging and an optional HTTP wire trace through log/slog
+//
+// A single Client should be created per organization and reused for
+// all calls so that TLS connections are kept alive during fan-out.
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"LC_utils/internal/auth"
+	"LC_utils/internal/logging"
+)
+
+const (
+	// DefaultBaseURL is the LimaCharlie REST API endpoint
+	DefaultBaseURL = "https://api.limacharlie.io"
+	// DefaultUserAgent is sent when no user agent is configured
+	DefaultUserAgent = "LC_utils"
+)
+
+// Client is a LimaCharlie API client bound to a set of credentials.
+// It owns the HTTP transport, so it is safe and cheap to share between
+// goroutines.
+type Client struct {
+	// creds are the credentials used to authenticate requests
+	creds *auth.Credentials
+	// httpClient is shared by every request made through this client
+	httpClient *http.Client
+	// baseURL is the API endpoint, without a trailing slash
+	baseURL string
+	// jwtEndpoint overrides the credentials' JWT endpoint when set
+	jwtEndpoint string
+	// userAgent is sent in the User-Agent header of every request
+	userAgent string
+	// retryPolicy controls retries of failed requests
+	retryPolicy RetryPolicy
+	// retries counts the requests that were retried
+	retries atomic.Int64
+	// logger receives debug records; it never receives secrets unredacted
+	// unless the caller configured it to
+	logger *slog.Logger
+	// trace wraps the transport with a wire trace when set
+	trace *logging.TraceTransport
+	// limiter paces every request, including retries, when set
+	limiter RateLimiter
+}
+
+// RateLimiter paces requests. Wait blocks until the next request may
+// start or ctx is cancelled.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at a different API endpoint, for example
+// a regional deployment or a local mock server.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithJWTEndpoint sets the endpoint used to exchange the API key for a JWT.
+func WithJWTEndpoint(endpoint string) Option {
+	return func(c *Client) {
+		c.jwtEndpoint = endpoint
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		if userAgent != "" {
+			c.userAgent = userAgent
+		}
+	}
+}
+
+// WithRateLimiter makes every request wait for limiter before it is
+// sent, including retries and the repeat after a JWT refresh. Clients
+// sharing a limiter share its rate.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithHTTPClient replaces the default pooled HTTP client.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithLogger sets the logger used for debug records and HTTP traces.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithHTTPTrace logs every request and response, including headers and
+// bodies, at logging.LevelTrace. Secrets are redacted unless showSecrets
+// is set.
+func WithHTTPTrace(showSecrets bool) Option {
+	return func(c *Client) {
+		c.trace = &logging.TraceTransport{ShowSecrets: showSecrets}
+	}
+}
+
+// NewClient creates a new Client for the given credentials. The
+// credentials are configured to fetch their JWT through the same
+// transport and endpoint settings as the client.
+//
+// Parameters:
+//   - creds: Authentication credentials for the API
+//   - opts: Optional client configuration
+//
+// Returns:
+//   - *Client: A new API client
+func NewClient(creds *auth.Credentials, opts ...Option) *Client {
+	c := &Client{
+		creds:       creds,
+		httpClient:  &http.Client{Transport: newTransport()},
+		baseURL:     DefaultBaseURL,
+		userAgent:   DefaultUserAgent,
+		retryPolicy: DefaultRetryPolicy(),
+		logger:      logging.Discard(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.trace != nil {
+		c.trace.Base = c.httpClient.Transport
+		c.trace.Logger = c.logger
+		c.trace.APIHosts = apiHosts(c.baseURL, c.jwtEndpoint, auth.DefaultJWTEndpoint)
+		traced := *c.httpClient
+		traced.Transport = c.trace
+		c.httpClient = &traced
+	}
+
+	creds.SetHTTPClient(c.httpClient)
+	creds.SetUserAgent(c.userAgent)
+	if c.jwtEndpoint != "" {
+		creds.SetJWTEndpoint(c.jwtEndpoint)
+	}
+
+	return c
+}
+
+// apiHosts returns the hosts of the given endpoints, skipping empty and
+// invalid ones.
+func apiHosts(endpoints ...string) []string {
+	var hosts []string
+	for _, endpoint := range endpoints {
+		if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	return hosts
+}
+
+// newTransport returns an HTTP transport tuned for many concurrent
+// requests against a single host.
+func newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = 100
+	t.MaxIdleConnsPerHost = 32
+	t.IdleConnTimeout = 90 * time.Second
+	return t
+}
+
+// Credentials returns the credentials used by the client.
+func (c *Client) Credentials() *auth.Credentials {
+	return c.creds
+}
+
+// OID returns the organization ID the client operates on.
+func (c *Client) OID() string {
+	return c.creds.OID
+}
+
+// BaseURL returns the API endpoint used by the client.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// newRequest creates an authenticated API request. The path is appended
+// to the client's base URL.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the request
+//   - method: HTTP method
+//   - path: Request path including any query string, starting with "/"
+//   - body: Optional request body
+//
+// Returns:
+//   - *http.Request: The prepared request
+//   - error: Any error that occurred while building the request
+func (c *Client) newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	// Set JWT in Authorization header
+	authHeader, err := c.creds.GetAuthHeader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting auth header: %w", err)
+	}
+	req.Header.Set("Authorization", authHeader)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	return req, nil
+}
+
+// do sends a request using the client's shared HTTP client, retrying
+// it according to the client's retry policy and the given mode. A 401
+// response to an authenticated request triggers one JWT refresh and
+// retry. The request body must be rewindable (see http.Request.GetBody)
+// for a request with a body to be retried.
+func (c *Client) do(req *http.Request, mode retryMode) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	rewindable := req.Body == nil || req.GetBody != nil
+	reauthenticated := false
+
+	for attempt := 0; ; {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(req.Context()); err != nil {
+				return nil, fmt.Errorf("error making request: %w", err)
+			}
+		}
+		resp, err := c.httpClient.Do(req)
+
+		// The cached JWT may have been revoked or expired early; get a
+		// new one and repeat the request once
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && !reauthenticated && rewindable &&
+			strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+			reauthenticated = true
+			c.logger.Debug("request unauthorized, refreshing JWT",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+			)
+			discardBody(resp)
+			c.creds.InvalidateJWT(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+			authHeader, authErr := c.creds.GetAuthHeader(req.Context())
+			if authErr != nil {
+				return nil, fmt.Errorf("error refreshing auth header: %w", authErr)
+			}
+			req.Header.Set("Authorization", authHeader)
+			if err := rewindBody(req); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		canRetry := attempt < c.retryPolicy.MaxRetries &&
+			req.Context().Err() == nil &&
+			rewindable &&
+			shouldRetry(mode, resp, err)
+		if !canRetry {
+			if err != nil {
+				return nil, fmt.Errorf("error making request: %w", err)
+			}
+			return resp, nil
+		}
+
+		delay := c.retryPolicy.delay(attempt, resp)
+		c.logger.Debug("retrying request",
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.Int("attempt", attempt+1),
+			slog.Duration("delay", delay),
+			slog.Any("status", statusOf(resp)),
+			slog.Any("error", err),
+		)
+		discardBody(resp)
+		c.retries.Add(1)
+		attempt++
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, fmt.Errorf("error making request: %w", req.Context().Err())
+		}
+
+		if err := rewindBody(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// statusOf returns the status code of resp, or 0 if there is none.
+func statusOf(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+// rewindBody resets the request body so the request can be sent again.
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return fmt.Errorf("error rewinding request body: %w", err)
+	}
+	req.Body = body
+	return nil
+}
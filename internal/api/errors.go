@@ -0,0 +1,147 @@
+// Package api provides typed errors for LimaCharlie API calls.
+// This file implements error-related functionality including:
+// - APIError, describing a failed request and the server's response
+// - Sentinel errors for common failure classes, usable with errors.Is
+//
+// Example usage:
+//
+//	if errors.Is(err, api.ErrForbidden) {
+//	    var apiErr *api.APIError
+//	    errors.As(err, &apiErr)
+//	    fmt.Println("missing permission:", apiErr.Permission)
+//	}
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matched by APIError through errors.Is.
+var (
+	// ErrUnauthorized means the JWT was missing, expired or rejected (401)
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden means the credentials lack a required permission (403)
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound means the organization, sensor or resource does not exist (404)
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited means the request was rejected by rate limiting (429)
+	ErrRateLimited = errors.New("rate limited")
+	// ErrServer means the API failed to process the request (5xx)
+	ErrServer = errors.New("server error")
+)
+
+// maxErrorBody limits how much of a failed response is kept in an APIError.
+const maxErrorBody = 4 << 10
+
+// APIError describes a request that the LimaCharlie API answered with a
+// non-success status.
+type APIError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Method is the HTTP method of the request
+	Method string
+	// Endpoint is the request path, without the query string
+	Endpoint string
+	// RequestID is the server-assigned request identifier, if any
+	RequestID string
+	// Message is the error message parsed from the response body
+	Message string
+	// Permission is the LimaCharlie permission the operation requires
+	Permission string
+	// Body is the raw response body, truncated
+	Body string
+}
+
+// Error returns a one-line description of the failure.
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s: %d %s", e.Method, e.Endpoint, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		fmt.Fprintf(&sb, ": %s", e.Message)
+	} else if e.Body != "" {
+		fmt.Fprintf(&sb, ": %s", e.Body)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&sb, " (request ID: %s)", e.RequestID)
+	}
+	return sb.String()
+}
+
+// Is reports whether the error belongs to the class of the given
+// sentinel error.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// errorBody is the error payload returned by the LimaCharlie API.
+type errorBody struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// newAPIError builds an APIError from a failed response, consuming its
+// body.
+//
+// Parameters:
+//   - resp: The non-success response
+//   - permission: The permission the operation requires, if known
+//
+// Returns:
+//   - *APIError: The error describing the failure
+func newAPIError(resp *http.Response, permission string) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return newAPIErrorFromBody(resp, body, permission)
+}
+
+// newAPIErrorFromBody builds an APIError from a failed response whose
+// body has already been read.
+func newAPIErrorFromBody(resp *http.Response, body []byte, permission string) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Permission: permission,
+		Body:       strings.TrimSpace(string(body)),
+	}
+	if len(apiErr.Body) > maxErrorBody {
+		apiErr.Body = apiErr.Body[:maxErrorBody]
+	}
+	if req := resp.Request; req != nil {
+		apiErr.Method = req.Method
+		apiErr.Endpoint = req.URL.Path
+	}
+
+	// LimaCharlie does not document a request ID header; fall back to
+	// the load balancer's trace header
+	for _, header := range []string{"X-Request-Id", "X-Cloud-Trace-Context"} {
+		if id := resp.Header.Get(header); id != "" {
+			apiErr.RequestID = id
+			break
+		}
+	}
+
+	var parsed errorBody
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		apiErr.Message = parsed.Error
+		if apiErr.Message == "" {
+			apiErr.Message = parsed.Message
+		}
+	}
+
+	return apiErr
+}
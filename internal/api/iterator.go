@@ -0,0 +1,321 @@
+// Package api provides sensor iteration functionality for LimaCharlie.
+// This file implements paginated sensor listing including:
+// - A SensorIterator that follows continuation tokens across pages
+// - Streaming JSON decoding, one sensor at a time
+// - ForEachSensor, a callback wrapper around the iterator
+//
+// Example usage:
+//
+//	it := client.Sensors(ctx, &api.ListOptions{WithTags: true})
+//	defer it.Close()
+//	for it.Next() {
+//	    fmt.Println(it.Sensor().Hostname)
+//	}
+//	if err := it.Err(); err != nil {
+//	    return err
+//	}
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// SensorIterator walks every sensor of an organization, fetching pages
+// as needed and decoding each page as a stream, so memory use does not
+// grow with the size of the organization. It is not safe for concurrent
+// use.
+type SensorIterator struct {
+	client *Client
+	ctx    context.Context
+	opts   ListOptions
+
+	// token is the continuation token of the next page, if any
+	token string
+	// pageToken is the continuation token the current page was requested with
+	pageToken string
+	// started reports whether the first page was requested
+	started bool
+	// done reports whether iteration has finished
+	done bool
+
+	// resp and dec read the current page
+	resp *http.Response
+	dec  *json.Decoder
+	// inSensors reports whether dec is inside the page's sensors array
+	inSensors bool
+
+	current Sensor
+	count   int
+	pages   int
+	err     error
+}
+
+// Sensors returns an iterator over the sensors matching opts. No request
+// is made until Next is called. When opts.Limit is set, it is used as
+// the page size and iteration stops after that many sensors.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of every page request
+//   - opts: Optional filtering and pagination parameters
+//
+// Returns:
+//   - *SensorIterator: An iterator positioned before the first sensor
+func (c *Client) Sensors(ctx context.Context, opts *ListOptions) *SensorIterator {
+	it := &SensorIterator{client: c, ctx: ctx}
+	if opts != nil {
+		it.opts = *opts
+		it.token = opts.ContinuationToken
+	}
+	return it
+}
+
+// Next advances to the next matching sensor. It returns false when
+// there are no more sensors or an error occurred; check Err afterwards.
+func (it *SensorIterator) Next() bool {
+	for !it.done {
+		if it.opts.Limit > 0 && it.count >= it.opts.Limit {
+			it.finish(nil)
+			break
+		}
+
+		if it.dec == nil {
+			if it.started && it.token == "" {
+				it.finish(nil)
+				break
+			}
+			// Guard against a server returning the same page forever
+			if it.started && it.token == it.pageToken {
+				it.finish(fmt.Errorf("continuation token %q did not advance", it.token))
+				break
+			}
+			if err := it.fetchPage(); err != nil {
+				it.finish(err)
+				break
+			}
+		}
+
+		sensor, ok, err := it.nextInPage()
+		if err != nil {
+			it.finish(err)
+			break
+		}
+		if !ok {
+			it.closePage()
+			continue
+		}
+		if !it.opts.matches(sensor) {
+			continue
+		}
+
+		it.current = sensor
+		it.count++
+		return true
+	}
+	return false
+}
+
+// Sensor returns the sensor at the current position.
+func (it *SensorIterator) Sensor() Sensor {
+	return it.current
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *SensorIterator) Err() error {
+	return it.err
+}
+
+// Pages returns the number of pages requested so far.
+func (it *SensorIterator) Pages() int {
+	return it.pages
+}
+
+// Close stops iteration and releases the current response. It is safe
+// to call more than once and after Next returned false.
+func (it *SensorIterator) Close() error {
+	it.finish(nil)
+	return nil
+}
+
+// finish ends iteration, keeping the first error seen.
+func (it *SensorIterator) finish(err error) {
+	if it.err == nil {
+		it.err = err
+	}
+	it.done = true
+	it.closePage()
+}
+
+// closePage releases the current page's response.
+func (it *SensorIterator) closePage() {
+	if it.resp != nil {
+		it.resp.Body.Close()
+	}
+	it.resp = nil
+	it.dec = nil
+	it.inSensors = false
+}
+
+// fetchPage requests the page identified by the current continuation
+// token and positions the decoder at the start of the response object.
+func (it *SensorIterator) fetchPage() error {
+	q := url.Values{}
+	if it.opts.Limit > 0 {
+		q.Set("limit", fmt.Sprintf("%d", it.opts.Limit))
+	}
+	// Always fetch tags if we need them for filtering
+	if it.opts.WithTags || it.opts.FilterTag != "" {
+		q.Set("with_tags", "true")
+	}
+	if it.opts.WithIP != "" {
+		q.Set("with_ip", it.opts.WithIP)
+	}
+	if it.opts.WithHostnamePrefix != "" {
+		q.Set("with_hostname_prefix", it.opts.WithHostnamePrefix)
+	}
+	if it.token != "" {
+		q.Set("continuation_token", it.token)
+	}
+
+	path := fmt.Sprintf("/v1/sensors/%s", url.PathEscape(it.client.OID()))
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	req, err := it.client.newRequest(it.ctx, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+
+	it.started = true
+	it.pages++
+	it.pageToken = it.token
+	it.token = ""
+	it.client.logger.Debug("fetching sensor page", slog.Int("page", it.pages))
+
+	resp, err := it.client.do(req, retryIdempotent)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return newAPIError(resp, "sensor.list")
+	}
+
+	it.resp = resp
+	it.dec = json.NewDecoder(resp.Body)
+	if err := expectDelim(it.dec, '{'); err != nil {
+		return err
+	}
+	return nil
+}
+
+// nextInPage decodes the next sensor of the current page. It returns
+// false once the page's response object has been read completely.
+func (it *SensorIterator) nextInPage() (Sensor, bool, error) {
+	for {
+		if it.inSensors {
+			if it.dec.More() {
+				var sensor Sensor
+				if err := it.dec.Decode(&sensor); err != nil {
+					return Sensor{}, false, fmt.Errorf("error decoding sensor: %w", err)
+				}
+				return sensor, true, nil
+			}
+			if err := expectDelim(it.dec, ']'); err != nil {
+				return Sensor{}, false, err
+			}
+			it.inSensors = false
+		}
+
+		if !it.dec.More() {
+			if err := expectDelim(it.dec, '}'); err != nil {
+				return Sensor{}, false, err
+			}
+			return Sensor{}, false, nil
+		}
+
+		tok, err := it.dec.Token()
+		if err != nil {
+			return Sensor{}, false, fmt.Errorf("error decoding response: %w", err)
+		}
+		switch tok {
+		case "sensors":
+			if err := expectDelim(it.dec, '['); err != nil {
+				return Sensor{}, false, err
+			}
+			it.inSensors = true
+		case "continuation_token":
+			var token *string
+			if err := it.dec.Decode(&token); err != nil {
+				return Sensor{}, false, fmt.Errorf("error decoding continuation token: %w", err)
+			}
+			if token != nil {
+				it.token = *token
+			}
+		default:
+			var skip json.RawMessage
+			if err := it.dec.Decode(&skip); err != nil {
+				return Sensor{}, false, fmt.Errorf("error decoding response: %w", err)
+			}
+		}
+	}
+}
+
+// expectDelim reads the next token and checks that it is delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	if tok != delim {
+		return fmt.Errorf("error decoding response: expected %q, got %v", delim, tok)
+	}
+	return nil
+}
+
+// matches applies the client-side filters of opts to a sensor.
+func (opts *ListOptions) matches(sensor Sensor) bool {
+	// Filter by online status if requested
+	if opts.OnlyOnline && !sensor.IsOnline {
+		return false
+	}
+
+	// Filter by tag if specified
+	if opts.FilterTag != "" {
+		for _, tag := range sensor.Tags {
+			if tag == opts.FilterTag {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// ForEachSensor calls fn for every sensor matching opts, following all
+// pages. Iteration stops at the first error returned by fn, which is
+// returned unchanged.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of every page request
+//   - opts: Optional filtering and pagination parameters
+//   - fn: Function called once per sensor, in API order
+//
+// Returns:
+//   - error: The first error from the API or from fn
+func (c *Client) ForEachSensor(ctx context.Context, opts *ListOptions, fn func(Sensor) error) error {
+	it := c.Sensors(ctx, opts)
+	defer it.Close()
+
+	for it.Next() {
+		if err := fn(it.Sensor()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
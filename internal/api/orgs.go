@@ -0,0 +1,93 @@
+// Package api provides organization discovery for LimaCharlie users.
+// This file implements user-level operations including:
+// - Listing the organizations a user API key or user JWT can access
+//
+// These calls are not bound to the client's organization, so they work
+// with user credentials that have no organization ID.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// UserOrg is an organization the authenticated user can access.
+type UserOrg struct {
+	OID  string `json:"oid"`
+	Name string `json:"name,omitempty"`
+}
+
+// userOrgsResponse is the response of the user organizations endpoint.
+// Orgs is either a list of OIDs, with names in Names, or a list of
+// objects carrying both.
+type userOrgsResponse struct {
+	Orgs  json.RawMessage   `json:"orgs"`
+	Names map[string]string `json:"names"`
+}
+
+// UserOrgs retrieves the organizations the credentials' user can access.
+// The result is sorted by name, then OID.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the request
+//
+// Returns:
+//   - []UserOrg: The accessible organizations
+//   - error: Any error that occurred during the operation
+func (c *Client) UserOrgs(ctx context.Context) ([]UserOrg, error) {
+	req, err := c.newRequest(ctx, "GET", "/v1/user/orgs", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make request
+	resp, err := c.do(req, retryIdempotent)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIErrorFromBody(resp, body, "")
+	}
+
+	var response userOrgsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	var orgs []UserOrg
+	if len(response.Orgs) > 0 {
+		var oids []string
+		if err := json.Unmarshal(response.Orgs, &oids); err == nil {
+			for _, oid := range oids {
+				orgs = append(orgs, UserOrg{OID: oid})
+			}
+		} else if err := json.Unmarshal(response.Orgs, &orgs); err != nil {
+			return nil, fmt.Errorf("error decoding organizations: %w", err)
+		}
+	}
+	for i := range orgs {
+		if orgs[i].Name == "" {
+			orgs[i].Name = response.Names[orgs[i].OID]
+		}
+	}
+
+	sort.Slice(orgs, func(i, j int) bool {
+		if orgs[i].Name != orgs[j].Name {
+			return orgs[i].Name < orgs[j].Name
+		}
+		return orgs[i].OID < orgs[j].OID
+	})
+	return orgs, nil
+}
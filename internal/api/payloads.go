@@ -10,19 +10,14 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
-
-	"LC_utils/internal/auth"
-)
-
-const (
-	payloadEndpoint = "https://api.limacharlie.io/v1/payload"
 )
 
 // PayloadUploadResponse represents the response from the payload upload request.
@@ -38,48 +33,32 @@ type PayloadUploadResponse struct {
 // 2. Upload the file contents to the provided URL
 //
 // Parameters:
-//   - orgID: Organization ID
-//   - apiKey: API Key for authentication
+//   - ctx: Context controlling cancellation of both upload steps
 //   - filePath: Path to the file to upload
 //
 // Returns:
 //   - error: Any error that occurred during the operation
-func UploadPayload(orgID string, apiKey string, filePath string) error {
-	// Create credentials
-	creds := auth.NewCredentials(orgID, apiKey)
-	if err := creds.ValidateCredentials(); err != nil {
-		return fmt.Errorf("invalid credentials: %w", err)
-	}
-
+func (c *Client) UploadPayload(ctx context.Context, filePath string) error {
 	// Get file name from path
 	fileName := filepath.Base(filePath)
-	url := fmt.Sprintf("%s/%s/%s", payloadEndpoint, orgID, fileName)
+	path := fmt.Sprintf("/v1/payload/%s/%s", url.PathEscape(c.OID()), url.PathEscape(fileName))
 
 	// Create request
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
-	}
-
-	// Set API key in Authorization header
-	authHeader, err := creds.GetAuthHeader()
+	req, err := c.newRequest(ctx, "POST", path, nil)
 	if err != nil {
-		return fmt.Errorf("error getting auth header: %w", err)
+		return err
 	}
-	req.Header.Set("Authorization", authHeader)
 	req.Header.Set("Content-Type", "application/json")
 
 	// Make request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req, retryIdempotent)
 	if err != nil {
-		return fmt.Errorf("error making request: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("error getting upload URL: status=%d, body=%s", resp.StatusCode, string(body))
+		return fmt.Errorf("error getting upload URL: %w", newAPIError(resp, "payload.ctrl"))
 	}
 
 	var uploadResp PayloadUploadResponse
@@ -93,22 +72,21 @@ func UploadPayload(orgID string, apiKey string, filePath string) error {
 		return fmt.Errorf("error reading file: %w", err)
 	}
 
-	uploadReq, err := http.NewRequest("PUT", uploadResp.PutURL, bytes.NewReader(fileContent))
+	uploadReq, err := http.NewRequestWithContext(ctx, "PUT", uploadResp.PutURL, bytes.NewReader(fileContent))
 	if err != nil {
 		return fmt.Errorf("error creating upload request: %w", err)
 	}
 
 	uploadReq.Header.Set("Content-Type", "application/octet-stream")
 
-	resp2, err := client.Do(uploadReq)
+	resp2, err := c.do(uploadReq, retryIdempotent)
 	if err != nil {
 		return fmt.Errorf("error uploading file: %w", err)
 	}
 	defer resp2.Body.Close()
 
 	if resp2.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp2.Body)
-		return fmt.Errorf("error uploading file: status=%d, body=%s", resp2.StatusCode, string(body))
+		return fmt.Errorf("error uploading file: %w", newAPIError(resp2, ""))
 	}
 
 	return nil
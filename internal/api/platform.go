@@ -0,0 +1,248 @@
+// Package api provides the sensor platform and architecture types.
+// This file implements:
+// - Platform, the plat and ext_plat IDs of endpoint sensors and adapters
+// - Arch, the arch IDs of sensors
+// - Parsing of names and aliases such as mac, osx or win
+//
+// Each type has a canonical lower-case name used by filters (Name) and a
+// display name used in output (String). Parsing accepts either, ignoring
+// case, spaces, dashes and underscores, as well as the aliases and the
+// numeric ID, so any value shown in output can be used as a filter.
+//
+// Example usage:
+//
+//	p, err := api.ParsePlatform("osx")
+//	if sensor.PlatformID == p {
+//	    fmt.Println(p) // macOS
+//	}
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Platform identifies the operating system of an endpoint sensor or the
+// data source of an adapter.
+type Platform uint32
+
+// Platform IDs from LimaCharlie. Endpoint platforms come first, then the
+// adapters, which ingest logs from other products and clouds.
+const (
+	PlatformWindows     Platform = 0x10000000
+	PlatformLinux       Platform = 0x20000000
+	PlatformMacOS       Platform = 0x30000000
+	PlatformIOS         Platform = 0x40000000
+	PlatformAndroid     Platform = 0x50000000
+	PlatformChrome      Platform = 0x60000000
+	PlatformVPN         Platform = 0x70000000
+	PlatformText        Platform = 0x80000000
+	PlatformJSON        Platform = 0x90000000
+	PlatformGCP         Platform = 0xA0000000
+	PlatformAWS         Platform = 0xB0000000
+	PlatformCarbonBlack Platform = 0xC0000000
+	Platform1Password   Platform = 0xD0000000
+	PlatformOffice365   Platform = 0xE0000000
+	PlatformSophos      Platform = 0xF0000000
+)
+
+// Arch identifies the CPU architecture of a sensor, or marks adapters.
+type Arch int
+
+// Architecture IDs from LimaCharlie.
+const (
+	ArchX86       Arch = 1
+	ArchX64       Arch = 2
+	ArchARM       Arch = 3
+	ArchARM64     Arch = 4
+	ArchAlpine64  Arch = 5
+	ArchChromium  Arch = 6
+	ArchWireGuard Arch = 7
+	ArchARML      Arch = 8
+	ArchAdapter   Arch = 9
+)
+
+// idName is the canonical name, display name and aliases of an ID.
+type idName struct {
+	name    string
+	display string
+	aliases []string
+}
+
+var platformNames = map[Platform]idName{
+	PlatformWindows:     {"windows", "Windows", []string{"win", "microsoft"}},
+	PlatformLinux:       {"linux", "Linux", nil},
+	PlatformMacOS:       {"macos", "macOS", []string{"mac", "osx", "darwin"}},
+	PlatformIOS:         {"ios", "iOS", nil},
+	PlatformAndroid:     {"android", "Android", nil},
+	PlatformChrome:      {"chrome", "Chrome", []string{"chromeos"}},
+	PlatformVPN:         {"vpn", "VPN", nil},
+	PlatformText:        {"text", "Text", nil},
+	PlatformJSON:        {"json", "JSON", nil},
+	PlatformGCP:         {"gcp", "GCP", []string{"google_cloud"}},
+	PlatformAWS:         {"aws", "AWS", []string{"amazon"}},
+	PlatformCarbonBlack: {"carbon_black", "Carbon Black", []string{"cb"}},
+	Platform1Password:   {"1password", "1Password", nil},
+	PlatformOffice365:   {"office365", "Office 365", []string{"o365", "microsoft365", "m365"}},
+	PlatformSophos:      {"sophos", "Sophos", nil},
+}
+
+var archNames = map[Arch]idName{
+	ArchX86:       {"x86", "x86", []string{"i386", "386"}},
+	ArchX64:       {"x64", "x64", []string{"amd64", "x86_64"}},
+	ArchARM:       {"arm", "ARM", nil},
+	ArchARM64:     {"arm64", "ARM64", []string{"aarch64"}},
+	ArchAlpine64:  {"alpine64", "Alpine x64", nil},
+	ArchChromium:  {"chromium", "Chromium", nil},
+	ArchWireGuard: {"wireguard", "WireGuard", nil},
+	ArchARML:      {"arml", "ARML", nil},
+	ArchAdapter:   {"adapter", "Adapter", []string{"usp_adapter", "cloud"}},
+}
+
+// String returns the display name of the platform, e.g. "macOS", or
+// "Unknown (0x...)" for IDs without a name.
+func (p Platform) String() string {
+	if n, ok := platformNames[p]; ok {
+		return n.display
+	}
+	if p == 0 {
+		return "None"
+	}
+	return fmt.Sprintf("Unknown (0x%x)", uint32(p))
+}
+
+// Name returns the canonical name of the platform, e.g. "macos", or the
+// hexadecimal ID for platforms without a name. It is empty for 0.
+func (p Platform) Name() string {
+	if n, ok := platformNames[p]; ok {
+		return n.name
+	}
+	if p == 0 {
+		return ""
+	}
+	return fmt.Sprintf("0x%x", uint32(p))
+}
+
+// IsAdapter reports whether the platform is an adapter data source
+// rather than an endpoint operating system.
+func (p Platform) IsAdapter() bool {
+	return p >= PlatformText || (p != 0 && p < PlatformWindows)
+}
+
+// ParsePlatform parses a platform name, display name, alias or numeric
+// ID, e.g. "macos", "macOS", "osx" or "0x30000000".
+//
+// Parameters:
+//   - s: The platform to parse
+//
+// Returns:
+//   - Platform: The platform
+//   - error: An error listing the known names if s is not a platform
+func ParsePlatform(s string) (Platform, error) {
+	key := normalizeName(s)
+	for p, n := range platformNames {
+		if n.matches(key) {
+			return p, nil
+		}
+	}
+	if id, err := strconv.ParseUint(strings.TrimSpace(s), 0, 32); err == nil && id != 0 {
+		return Platform(id), nil
+	}
+	return 0, fmt.Errorf("unknown platform %q, use one of: %s", s, strings.Join(PlatformNames(), ", "))
+}
+
+// PlatformNames returns the canonical names of the known platforms.
+func PlatformNames() []string {
+	ids := make([]Platform, 0, len(platformNames))
+	for p := range platformNames {
+		ids = append(ids, p)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	names := make([]string, len(ids))
+	for i, p := range ids {
+		names[i] = platformNames[p].name
+	}
+	return names
+}
+
+// String returns the display name of the architecture, e.g. "ARM64", or
+// "Unknown (n)" for IDs without a name.
+func (a Arch) String() string {
+	if n, ok := archNames[a]; ok {
+		return n.display
+	}
+	return fmt.Sprintf("Unknown (%d)", int(a))
+}
+
+// Name returns the canonical name of the architecture, e.g. "arm64", or
+// its number for architectures without a name.
+func (a Arch) Name() string {
+	if n, ok := archNames[a]; ok {
+		return n.name
+	}
+	return strconv.Itoa(int(a))
+}
+
+// ParseArch parses an architecture name, display name, alias or number,
+// e.g. "arm64", "aarch64" or "4".
+//
+// Parameters:
+//   - s: The architecture to parse
+//
+// Returns:
+//   - Arch: The architecture
+//   - error: An error listing the known names if s is not an architecture
+func ParseArch(s string) (Arch, error) {
+	key := normalizeName(s)
+	for a, n := range archNames {
+		if n.matches(key) {
+			return a, nil
+		}
+	}
+	if id, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && id > 0 {
+		return Arch(id), nil
+	}
+	return 0, fmt.Errorf("unknown architecture %q, use one of: %s", s, strings.Join(ArchNames(), ", "))
+}
+
+// ArchNames returns the canonical names of the known architectures.
+func ArchNames() []string {
+	ids := make([]Arch, 0, len(archNames))
+	for a := range archNames {
+		ids = append(ids, a)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	names := make([]string, len(ids))
+	for i, a := range ids {
+		names[i] = archNames[a].name
+	}
+	return names
+}
+
+// matches reports whether a normalized name is the canonical name,
+// display name or an alias.
+func (n idName) matches(key string) bool {
+	if key == normalizeName(n.name) || key == normalizeName(n.display) {
+		return true
+	}
+	for _, alias := range n.aliases {
+		if key == normalizeName(alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeName lower-cases a name and drops spaces, dashes and
+// underscores, so that "Carbon Black" and "carbon_black" are equal.
+func normalizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(s)))
+}
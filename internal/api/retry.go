@@ -0,0 +1,130 @@
+// Package api provides retry functionality for LimaCharlie API calls.
+// This file implements the retry policy including:
+// - Exponential backoff with jitter
+// - Honoring the Retry-After header on 429 and 503 responses
+// - Restricting retries of non-idempotent calls to rate-limit rejections
+//
+// Retries are counted per client so that commands can report them in
+// their summaries.
+package api
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// BaseDelay is the backoff delay before the first retry
+	BaseDelay time.Duration
+	// MaxDelay caps a single backoff delay, including Retry-After values
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// WithRetryPolicy sets the retry policy used by the client. A policy with
+// MaxRetries of zero disables retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// retryMode describes how safe it is to repeat a request.
+type retryMode int
+
+const (
+	// retryIdempotent retries on network errors, 429 and 5xx responses.
+	// Use it for reads and for writes that can safely be repeated.
+	retryIdempotent retryMode = iota
+	// retryRateLimited only retries 429 responses, where the server has
+	// rejected the request before acting on it. Use it for calls such as
+	// tasking that must not run twice.
+	retryRateLimited
+)
+
+// shouldRetry reports whether a request that produced resp or err may be
+// attempted again under the given mode.
+func shouldRetry(mode retryMode, resp *http.Response, err error) bool {
+	if err != nil {
+		return mode == retryIdempotent
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return mode == retryIdempotent && resp.StatusCode >= 500
+}
+
+// delay returns how long to wait before retry number attempt (starting
+// at 0). A Retry-After header on resp takes precedence over the
+// exponential backoff.
+func (p RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return p.clamp(d)
+		}
+	}
+
+	backoff := p.BaseDelay << attempt
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	// Equal jitter: wait between half and all of the backoff so that
+	// concurrent workers do not retry in lockstep
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// clamp limits d to the policy's maximum delay.
+func (p RetryPolicy) clamp(d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or
+// as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// discardBody drains and closes a response body so that the underlying
+// connection can be reused for the retry.
+func discardBody(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
+// Retries returns the number of requests this client has retried.
+func (c *Client) Retries() int64 {
+	return c.retries.Load()
+}
@@ -4,80 +4,78 @@
 // - Checking online status
 // - Managing sensor tags
 // - Retrieving sensor details
+// - Isolating sensors from the network and rejoining them
+// - Sealing and unsealing sensors
+// - Deleting sensors
 //
 // Each function is designed to handle errors gracefully and provide
 // meaningful error messages for troubleshooting.
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
-
-	"LC_utils/internal/auth"
 )
 
 // ListSensors retrieves all sensors from LimaCharlie platform.
 // It supports filtering by various criteria through the ListOptions parameter.
-// The function handles pagination and online status filtering internally.
+// The function follows every page of results and applies online status
+// and tag filtering internally. Use Sensors or ForEachSensor to process
+// large organizations without holding every sensor in memory.
 //
 // Parameters:
-//   - creds: Authentication credentials for the API
+//   - ctx: Context controlling cancellation of the request
 //   - opts: Optional filtering and pagination parameters
 //
 // Returns:
 //   - []Sensor: List of sensors matching the criteria
 //   - error: Any error that occurred during the operation
-func ListSensors(creds *auth.Credentials, opts *ListOptions) ([]Sensor, error) {
-	// Build URL with query parameters
-	u, err := url.Parse(fmt.Sprintf("%s/v1/sensors/%s", baseURL, creds.OID))
+func (c *Client) ListSensors(ctx context.Context, opts *ListOptions) ([]Sensor, error) {
+	var sensors []Sensor
+	err := c.ForEachSensor(ctx, opts, func(sensor Sensor) error {
+		sensors = append(sensors, sensor)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error parsing URL: %w", err)
+		return nil, err
 	}
+	return sensors, nil
+}
 
-	// Add query parameters
-	q := u.Query()
-	if opts != nil {
-		if opts.Limit > 0 {
-			q.Set("limit", fmt.Sprintf("%d", opts.Limit))
-		}
-		// Always fetch tags if we need them for filtering
-		if opts.WithTags || opts.FilterTag != "" {
-			q.Set("with_tags", "true")
-		}
-		if opts.WithIP != "" {
-			q.Set("with_ip", opts.WithIP)
-		}
-		if opts.WithHostnamePrefix != "" {
-			q.Set("with_hostname_prefix", opts.WithHostnamePrefix)
-		}
-		if opts.ContinuationToken != "" {
-			q.Set("continuation_token", opts.ContinuationToken)
-		}
-	}
-	u.RawQuery = q.Encode()
-
-	// Create request
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
+// sensorInfoResponse is the response of the per-sensor endpoint.
+type sensorInfoResponse struct {
+	Info     Sensor `json:"info"`
+	IsOnline bool   `json:"is_online"`
+}
 
-	// Set API key in Authorization header
-	authHeader, err := creds.GetAuthHeader()
+// GetSensor retrieves the full details of a single sensor, including its
+// isolation and seal state, kernel availability and installer version.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the request
+//   - sensorID: ID of the sensor to retrieve
+//
+// Returns:
+//   - *Sensor: The sensor's details
+//   - error: Any error that occurred during the operation, matching
+//     ErrNotFound if the sensor does not exist
+func (c *Client) GetSensor(ctx context.Context, sensorID string) (*Sensor, error) {
+	path := fmt.Sprintf("/v1/%s", url.PathEscape(sensorID))
+	req, err := c.newRequest(ctx, "GET", path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error getting auth header: %w", err)
+		return nil, err
 	}
-	req.Header.Set("Authorization", authHeader)
 
 	// Make request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req, retryIdempotent)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -88,46 +86,18 @@ func ListSensors(creds *auth.Credentials, opts *ListOptions) ([]Sensor, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status: %d, body: %s", resp.StatusCode, string(body))
+		return nil, newAPIErrorFromBody(resp, body, "sensor.get")
 	}
 
-	var sensorList SensorList
-	if err := json.Unmarshal(body, &sensorList); err != nil {
+	var response sensorInfoResponse
+	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, fmt.Errorf("error decoding response: %w", err)
 	}
 
-	// Filter sensors based on criteria
-	var filteredSensors []Sensor
-	for _, sensor := range sensorList.Sensors {
-		include := true
-
-		// Filter by online status if requested
-		if opts != nil && opts.OnlyOnline && !sensor.IsOnline {
-			include = false
-			continue
-		}
-
-		// Filter by tag if specified
-		if opts != nil && opts.FilterTag != "" {
-			tagFound := false
-			for _, tag := range sensor.Tags {
-				if tag == opts.FilterTag {
-					tagFound = true
-					break
-				}
-			}
-			if !tagFound {
-				include = false
-				continue
-			}
-		}
-
-		if include {
-			filteredSensors = append(filteredSensors, sensor)
-		}
-	}
-
-	return filteredSensors, nil
+	// The online state is reported next to the sensor info
+	sensor := response.Info
+	sensor.IsOnline = sensor.IsOnline || response.IsOnline
+	return &sensor, nil
 }
 
 // GetOnlineStatus retrieves the online status of multiple sensors.
@@ -135,38 +105,25 @@ func ListSensors(creds *auth.Credentials, opts *ListOptions) ([]Sensor, error) {
 // to check multiple sensors at once.
 //
 // Parameters:
-//   - creds: Authentication credentials for the API
+//   - ctx: Context controlling cancellation of the request
 //   - sensorIDs: List of sensor IDs to check
 //
 // Returns:
 //   - *OnlineStatusResponse: Map of sensor IDs to their online status
 //   - error: Any error that occurred during the operation
-func GetOnlineStatus(creds *auth.Credentials, sensorIDs []string) (*OnlineStatusResponse, error) {
-	// Build URL
-	u, err := url.Parse(fmt.Sprintf("%s/v1/sensors/%s/online", baseURL, creds.OID))
-	if err != nil {
-		return nil, fmt.Errorf("error parsing URL: %w", err)
-	}
-
+func (c *Client) GetOnlineStatus(ctx context.Context, sensorIDs []string) (*OnlineStatusResponse, error) {
 	// Create request
-	req, err := http.NewRequest("POST", u.String(), strings.NewReader(strings.Join(sensorIDs, ",")))
+	path := fmt.Sprintf("/v1/sensors/%s/online", url.PathEscape(c.OID()))
+	req, err := c.newRequest(ctx, "POST", path, strings.NewReader(strings.Join(sensorIDs, ",")))
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		return nil, err
 	}
-
-	// Set API key in Authorization header
-	authHeader, err := creds.GetAuthHeader()
-	if err != nil {
-		return nil, fmt.Errorf("error getting auth header: %w", err)
-	}
-	req.Header.Set("Authorization", authHeader)
 	req.Header.Set("Content-Type", "text/plain")
 
 	// Make request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req, retryIdempotent)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -177,7 +134,7 @@ func GetOnlineStatus(creds *auth.Credentials, sensorIDs []string) (*OnlineStatus
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status: %d, body: %s", resp.StatusCode, string(body))
+		return nil, newAPIErrorFromBody(resp, body, "sensor.list")
 	}
 
 	var response OnlineStatusResponse
@@ -199,100 +156,188 @@ type TagSensorRequest struct {
 // If both operations are requested, adds are performed before removes.
 //
 // Parameters:
-//   - creds: Authentication credentials for the API
+//   - ctx: Context controlling cancellation of the request
 //   - sensorID: ID of the sensor to modify
 //   - tags: TagSensorRequest containing tags to add and/or remove
 //
 // Returns:
 //   - error: Any error that occurred during the operation
-func TagSensor(creds *auth.Credentials, sensorID string, tags TagSensorRequest) error {
-	// Build URL
-	u, err := url.Parse(fmt.Sprintf("%s/v1/%s/tags", baseURL, sensorID))
-	if err != nil {
-		return fmt.Errorf("error parsing URL: %w", err)
-	}
+func (c *Client) TagSensor(ctx context.Context, sensorID string, tags TagSensorRequest) error {
+	path := fmt.Sprintf("/v1/%s/tags", url.PathEscape(sensorID))
 
-	// Add query parameters
-	q := u.Query()
 	if len(tags.AddTags) > 0 {
+		// Add query parameters
+		q := url.Values{}
 		for _, tag := range tags.AddTags {
 			q.Add("tags", tag)
 		}
-		// Create POST request for adding tags
-		u.RawQuery = q.Encode()
-		req, err := http.NewRequest("POST", u.String(), nil)
-		if err != nil {
-			return fmt.Errorf("error creating request: %w", err)
-		}
 
-		// Set API key in Authorization header
-		authHeader, err := creds.GetAuthHeader()
+		// Create POST request for adding tags
+		req, err := c.newRequest(ctx, "POST", path+"?"+q.Encode(), nil)
 		if err != nil {
-			return fmt.Errorf("error getting auth header: %w", err)
+			return err
 		}
-		fmt.Printf("[DEBUG] TagSensor - Auth Header: %s\n", authHeader[:20]+"...") // Only show first 20 chars for security
-		req.Header.Set("Authorization", authHeader)
 
 		// Make request
-		client := &http.Client{}
-		fmt.Printf("[DEBUG] TagSensor - Sending POST request to add tags for sensor %s...\n", sensorID)
-		fmt.Printf("[DEBUG] TagSensor - URL: %s\n", u.String())
-		resp, err := client.Do(req)
+		c.logger.Debug("adding sensor tags", slog.String("sid", sensorID), slog.Any("tags", tags.AddTags))
+		resp, err := c.do(req, retryIdempotent)
 		if err != nil {
-			return fmt.Errorf("error making request: %w", err)
+			return err
 		}
 		defer resp.Body.Close()
 
 		// Read response body
 		respBody, _ := io.ReadAll(resp.Body)
-		fmt.Printf("[DEBUG] TagSensor - Response Status: %d\n", resp.StatusCode)
-		fmt.Printf("[DEBUG] TagSensor - Response Body: %s\n", string(respBody))
+		c.logger.Debug("tag response", slog.String("sid", sensorID), slog.Int("status", resp.StatusCode))
 
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("request failed with status: %d, body: %s", resp.StatusCode, string(respBody))
+			return newAPIErrorFromBody(resp, respBody, "sensor.tag")
 		}
 	}
 
 	if len(tags.RemoveTags) > 0 {
-		// Reset query for remove operation
-		q = url.Values{}
 		// Join tags with commas for removal
+		q := url.Values{}
 		q.Set("tags", strings.Join(tags.RemoveTags, ","))
 
 		// Create DELETE request for removing tags
-		u.RawQuery = q.Encode()
-		req, err := http.NewRequest("DELETE", u.String(), nil)
+		req, err := c.newRequest(ctx, "DELETE", path+"?"+q.Encode(), nil)
 		if err != nil {
-			return fmt.Errorf("error creating request: %w", err)
+			return err
 		}
 
-		// Set API key in Authorization header
-		authHeader, err := creds.GetAuthHeader()
-		if err != nil {
-			return fmt.Errorf("error getting auth header: %w", err)
-		}
-		fmt.Printf("[DEBUG] TagSensor - Auth Header: %s\n", authHeader[:20]+"...") // Only show first 20 chars for security
-		req.Header.Set("Authorization", authHeader)
-
 		// Make request
-		client := &http.Client{}
-		fmt.Printf("[DEBUG] TagSensor - Sending DELETE request to remove tags for sensor %s...\n", sensorID)
-		fmt.Printf("[DEBUG] TagSensor - URL: %s\n", u.String())
-		resp, err := client.Do(req)
+		c.logger.Debug("removing sensor tags", slog.String("sid", sensorID), slog.Any("tags", tags.RemoveTags))
+		resp, err := c.do(req, retryIdempotent)
 		if err != nil {
-			return fmt.Errorf("error making request: %w", err)
+			return err
 		}
 		defer resp.Body.Close()
 
 		// Read response body
 		respBody, _ := io.ReadAll(resp.Body)
-		fmt.Printf("[DEBUG] TagSensor - Response Status: %d\n", resp.StatusCode)
-		fmt.Printf("[DEBUG] TagSensor - Response Body: %s\n", string(respBody))
+		c.logger.Debug("tag response", slog.String("sid", sensorID), slog.Int("status", resp.StatusCode))
 
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("request failed with status: %d, body: %s", resp.StatusCode, string(respBody))
+			return newAPIErrorFromBody(resp, respBody, "sensor.tag")
 		}
 	}
 
 	return nil
 }
+
+// IsolateSensor isolates a sensor from the network; only its connection
+// to LimaCharlie is kept. The change is applied when the sensor is next
+// online, so callers should poll GetSensor to confirm it.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the request
+//   - sensorID: ID of the sensor to isolate
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (c *Client) IsolateSensor(ctx context.Context, sensorID string) error {
+	return c.setSensorState(ctx, sensorID, "isolation", true)
+}
+
+// RejoinSensor ends the network isolation of a sensor. Like
+// IsolateSensor, the change takes effect asynchronously.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the request
+//   - sensorID: ID of the sensor to rejoin
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (c *Client) RejoinSensor(ctx context.Context, sensorID string) error {
+	return c.setSensorState(ctx, sensorID, "isolation", false)
+}
+
+// SealSensor enables tamper protection on a sensor, preventing it from
+// being stopped or uninstalled locally. The change takes effect when
+// the sensor is next online.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the request
+//   - sensorID: ID of the sensor to seal
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (c *Client) SealSensor(ctx context.Context, sensorID string) error {
+	return c.setSensorState(ctx, sensorID, "seal", true)
+}
+
+// UnsealSensor disables tamper protection on a sensor. Like SealSensor,
+// the change takes effect asynchronously.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the request
+//   - sensorID: ID of the sensor to unseal
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (c *Client) UnsealSensor(ctx context.Context, sensorID string) error {
+	return c.setSensorState(ctx, sensorID, "seal", false)
+}
+
+// setSensorState enables (POST) or disables (DELETE) a sensor setting
+// exposed at /v1/{sid}/{setting}, such as isolation or seal.
+func (c *Client) setSensorState(ctx context.Context, sensorID, setting string, enable bool) error {
+	method := "DELETE"
+	if enable {
+		method = "POST"
+	}
+	path := fmt.Sprintf("/v1/%s/%s", url.PathEscape(sensorID), setting)
+	req, err := c.newRequest(ctx, method, path, nil)
+	if err != nil {
+		return err
+	}
+
+	// Make request
+	c.logger.Debug("changing sensor state", slog.String("sid", sensorID), slog.String("setting", setting), slog.Bool("enable", enable))
+	resp, err := c.do(req, retryIdempotent)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return newAPIErrorFromBody(resp, body, "sensor.task")
+	}
+	return nil
+}
+
+// DeleteSensor permanently removes a sensor from the organization. A
+// sensor that is still installed must be re-enrolled to report again.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the request
+//   - sensorID: ID of the sensor to delete
+//
+// Returns:
+//   - error: Any error that occurred during the operation, matching
+//     ErrNotFound if the sensor does not exist
+func (c *Client) DeleteSensor(ctx context.Context, sensorID string) error {
+	path := fmt.Sprintf("/v1/%s", url.PathEscape(sensorID))
+	req, err := c.newRequest(ctx, "DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+
+	// Make request
+	c.logger.Debug("deleting sensor", slog.String("sid", sensorID))
+	resp, err := c.do(req, retryIdempotent)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return newAPIErrorFromBody(resp, body, "sensor.delete")
+	}
+	return nil
+}
@@ -10,21 +10,21 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
-
-	"LC_utils/internal/auth"
 )
 
 // PutCommand sends a PUT command to a sensor to upload a file.
 // This is a convenience wrapper around TaskSensor for file uploads.
 //
 // Parameters:
-//   - creds: Authentication credentials for the API
+//   - ctx: Context controlling cancellation of the request
 //   - sensorID: ID of the target sensor
 //   - path: Destination path on the sensor
 //   - content: Content to write to the file
@@ -33,16 +33,16 @@ import (
 // Returns:
 //   - *TaskResponse: Response from the task execution
 //   - error: Any error that occurred during the operation
-func PutCommand(creds *auth.Credentials, sensorID string, path string, content string, investigationID string) (*TaskResponse, error) {
+func (c *Client) PutCommand(ctx context.Context, sensorID string, path string, content string, investigationID string) (*TaskResponse, error) {
 	task := fmt.Sprintf("put %s %s", path, content)
-	return TaskSensor(creds, sensorID, []string{task}, investigationID)
+	return c.TaskSensor(ctx, sensorID, []string{task}, investigationID)
 }
 
 // RunCommand sends a RUN command to execute a shell command on a sensor.
 // This is a convenience wrapper around TaskSensor for command execution.
 //
 // Parameters:
-//   - creds: Authentication credentials for the API
+//   - ctx: Context controlling cancellation of the request
 //   - sensorID: ID of the target sensor
 //   - command: Shell command to execute
 //   - investigationID: Optional investigation ID for tracking
@@ -50,17 +50,17 @@ func PutCommand(creds *auth.Credentials, sensorID string, path string, content s
 // Returns:
 //   - *TaskResponse: Response from the task execution
 //   - error: Any error that occurred during the operation
-func RunCommand(creds *auth.Credentials, sensorID string, command string, investigationID string) (*TaskResponse, error) {
+func (c *Client) RunCommand(ctx context.Context, sensorID string, command string, investigationID string) (*TaskResponse, error) {
 	// Use --shell-command flag for running shell commands
 	task := fmt.Sprintf(`run --shell-command '%s'`, command)
-	return TaskSensor(creds, sensorID, []string{task}, investigationID)
+	return c.TaskSensor(ctx, sensorID, []string{task}, investigationID)
 }
 
 // TaskSensor sends a task to a sensor. This is the core function for
 // sending any type of task to a sensor.
 //
 // Parameters:
-//   - creds: Authentication credentials for the API
+//   - ctx: Context controlling cancellation of the request
 //   - sensorID: ID of the target sensor
 //   - tasks: List of tasks to execute
 //   - investigationID: Optional investigation ID for tracking
@@ -68,13 +68,7 @@ func RunCommand(creds *auth.Credentials, sensorID string, command string, invest
 // Returns:
 //   - *TaskResponse: Response from the task execution
 //   - error: Any error that occurred during the operation
-func TaskSensor(creds *auth.Credentials, sensorID string, tasks []string, investigationID string) (*TaskResponse, error) {
-	// Build URL
-	u, err := url.Parse(fmt.Sprintf("%s/v1/%s", baseURL, sensorID))
-	if err != nil {
-		return nil, fmt.Errorf("error parsing URL: %w", err)
-	}
-
+func (c *Client) TaskSensor(ctx context.Context, sensorID string, tasks []string, investigationID string) (*TaskResponse, error) {
 	// Prepare form data
 	form := url.Values{}
 	form.Add("tasks", strings.Join(tasks, ","))
@@ -83,24 +77,16 @@ func TaskSensor(creds *auth.Credentials, sensorID string, tasks []string, invest
 	}
 
 	// Create request
-	req, err := http.NewRequest("POST", u.String(), strings.NewReader(form.Encode()))
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/v1/%s", url.PathEscape(sensorID)), strings.NewReader(form.Encode()))
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		return nil, err
 	}
-
-	// Set API key in Authorization header
-	authHeader, err := creds.GetAuthHeader()
-	if err != nil {
-		return nil, fmt.Errorf("error getting auth header: %w", err)
-	}
-	req.Header.Set("Authorization", authHeader)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	// Make request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req, retryRateLimited)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -111,7 +97,7 @@ func TaskSensor(creds *auth.Credentials, sensorID string, tasks []string, invest
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status: %d, body: %s", resp.StatusCode, string(body))
+		return nil, newAPIErrorFromBody(resp, body, "sensor.task")
 	}
 
 	var response TaskResponse
@@ -130,27 +116,19 @@ func TaskSensor(creds *auth.Credentials, sensorID string, tasks []string, invest
 // This is used for advanced functionality like reliable tasking.
 //
 // Parameters:
-//   - creds: Authentication credentials for the API
+//   - ctx: Context controlling cancellation of the request
 //   - extensionName: Name of the extension to use
 //   - action: Action to perform
 //   - data: JSON-encoded data for the action
 //
 // Returns:
 //   - error: Any error that occurred during the operation
-func CreateExtensionRequest(creds *auth.Credentials, extensionName string, action string, data interface{}) error {
-	fmt.Printf("[DEBUG] CreateExtensionRequest - Received data: %+v\n", data)
-
-	// Build URL
-	u, err := url.Parse(fmt.Sprintf("%s/v1/extension/request/%s", baseURL, extensionName))
-	if err != nil {
-		return fmt.Errorf("error parsing URL: %w", err)
-	}
-
+func (c *Client) CreateExtensionRequest(ctx context.Context, extensionName string, action string, data interface{}) error {
 	// Add required query parameters
-	q := u.Query()
-	q.Set("oid", creds.OID)
+	q := url.Values{}
+	q.Set("oid", c.OID())
 	q.Set("action", action)
-	u.RawQuery = q.Encode()
+	path := fmt.Sprintf("/v1/extension/request/%s?%s", url.PathEscape(extensionName), q.Encode())
 
 	// Convert data to map if it's a string
 	var taskData map[string]interface{}
@@ -171,32 +149,27 @@ func CreateExtensionRequest(creds *auth.Credentials, extensionName string, actio
 		return fmt.Errorf("error encoding task data: %w", err)
 	}
 
-	// Before sending request
-	fmt.Printf("[DEBUG] CreateExtensionRequest - Final request data: %s\n", string(jsonData))
+	c.logger.Debug("sending extension request",
+		slog.String("extension", extensionName),
+		slog.String("action", action),
+		slog.String("data", string(jsonData)),
+	)
 
 	// Prepare form data
 	form := url.Values{}
 	form.Add("data", string(jsonData))
 
 	// Create request
-	req, err := http.NewRequest("POST", u.String(), strings.NewReader(form.Encode()))
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
-	}
-
-	// Set API key in Authorization header
-	authHeader, err := creds.GetAuthHeader()
+	req, err := c.newRequest(ctx, "POST", path, strings.NewReader(form.Encode()))
 	if err != nil {
-		return fmt.Errorf("error getting auth header: %w", err)
+		return err
 	}
-	req.Header.Set("Authorization", authHeader)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	// Make request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req, retryRateLimited)
 	if err != nil {
-		return fmt.Errorf("error making request: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -207,7 +180,7 @@ func CreateExtensionRequest(creds *auth.Credentials, extensionName string, actio
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("request failed with status: %d, body: %s", resp.StatusCode, string(body))
+		return newAPIErrorFromBody(resp, body, "")
 	}
 
 	return nil
@@ -217,32 +190,27 @@ func CreateExtensionRequest(creds *auth.Credentials, extensionName string, actio
 // Uses the ext-reliable-tasking extension to ensure task delivery.
 //
 // Parameters:
-//   - creds: Authentication credentials for the API
+//   - ctx: Context controlling cancellation of the request
 //   - sensorID: ID of the target sensor
 //   - command: Command to execute
-//   - context: Optional context for tracking retries
+//   - taskContext: Optional context for tracking retries
 //   - ttl: Time-to-live in seconds for the task
 //
 // Returns:
 //   - error: Any error that occurred during the operation
-func CreateReliableTask(creds *auth.Credentials, sensorID string, command string, context string, ttl int64) error {
-	fmt.Printf("[DEBUG] CreateReliableTask - Original command received: %q\n", command)
-
+func (c *Client) CreateReliableTask(ctx context.Context, sensorID string, command string, taskContext string, ttl int64) error {
 	// Prepare the task data
 	var taskCommand string
 
 	// Check if this is a put command or run command
 	if strings.HasPrefix(command, "put") {
 		taskCommand = command
-		fmt.Printf("[DEBUG] CreateReliableTask - PUT command detected, using as is: %q\n", taskCommand)
 	} else if strings.HasPrefix(command, "run --shell-command") {
 		// Command is already properly formatted
 		taskCommand = command
-		fmt.Printf("[DEBUG] CreateReliableTask - RUN command already formatted, using as is: %q\n", taskCommand)
 	} else {
 		// For run commands that need formatting
 		taskCommand = fmt.Sprintf("run --shell-command '%s'", command)
-		fmt.Printf("[DEBUG] CreateReliableTask - RUN command formatted: %q\n", taskCommand)
 	}
 
 	taskData := map[string]interface{}{
@@ -251,14 +219,19 @@ func CreateReliableTask(creds *auth.Credentials, sensorID string, command string
 		"sid":  sensorID,
 	}
 
-	fmt.Printf("[DEBUG] CreateReliableTask - Final task data: %+v\n", taskData)
-
 	// Add context if provided
-	if context != "" {
-		taskData["context"] = context
-		fmt.Printf("[DEBUG] CreateReliableTask - Added context: %q\n", context)
+	if taskContext != "" {
+		taskData["context"] = taskContext
 	}
 
+	c.logger.Debug("creating reliable task",
+		slog.String("sid", sensorID),
+		slog.String("command", command),
+		slog.String("task", taskCommand),
+		slog.String("context", taskContext),
+		slog.Int64("ttl", ttl),
+	)
+
 	// Send the request to the reliable tasking extension
-	return CreateExtensionRequest(creds, "ext-reliable-tasking", "task", taskData)
+	return c.CreateExtensionRequest(ctx, "ext-reliable-tasking", "task", taskData)
 }
@@ -11,29 +11,14 @@
 package api
 
 import (
-	"fmt"
-)
-
-const baseURL = "https://api.limacharlie.io"
-
-// Platform constants
-const (
-	PlatformWindows = "windows"
-	PlatformMacOS   = "mac"
-	PlatformLinux   = "linux"
-)
-
-// Architecture constants
-const (
-	ArchX86   = "x86"
-	ArchX64   = "x64"
-	ArchARM   = "arm"
-	ArchARM64 = "arm64"
+	"encoding/json"
+	"strconv"
+	"time"
 )
 
 // ListOptions contains parameters for filtering and paginating sensor lists
 type ListOptions struct {
-	// Limit the number of results
+	// Limit the number of results; also used as the page size
 	Limit int
 	// Include sensor tags in response
 	WithTags bool
@@ -43,7 +28,7 @@ type ListOptions struct {
 	WithHostnamePrefix string
 	// Only return online sensors
 	OnlyOnline bool
-	// Continuation token for pagination
+	// Continuation token of the page to start from
 	ContinuationToken string
 	// Filter by tag
 	FilterTag string
@@ -57,10 +42,10 @@ type Sensor struct {
 	SID string `json:"sid"`
 	// InstallationID is the unique installation identifier
 	InstallationID string `json:"did"`
-	// PlatformID identifies the operating system platform
-	PlatformID int64 `json:"plat"`
+	// PlatformID identifies the operating system platform or adapter
+	PlatformID Platform `json:"plat"`
 	// Architecture identifies the CPU architecture
-	Architecture int `json:"arch"`
+	Architecture Arch `json:"arch"`
 	// Hostname is the sensor's hostname
 	Hostname string `json:"hostname"`
 	// OID is the organization identifier
@@ -69,10 +54,14 @@ type Sensor struct {
 	Tags []string `json:"tags,omitempty"`
 	// IsOnline indicates if the sensor is currently connected
 	IsOnline bool `json:"is_online"`
-	// LastSeen is the timestamp of last contact
+	// LastSeen is the timestamp of last contact, as returned by the API
 	LastSeen string `json:"alive"`
-	// EnrollmentTime is when the sensor was enrolled
+	// EnrollmentTime is when the sensor was enrolled, as returned by the API
 	EnrollmentTime string `json:"enroll"`
+	// LastSeenAt is LastSeen parsed, in UTC; zero if unknown
+	LastSeenAt time.Time `json:"-"`
+	// EnrolledAt is EnrollmentTime parsed, in UTC; zero if unknown
+	EnrolledAt time.Time `json:"-"`
 	// ExternalIP is the sensor's external IP address
 	ExternalIP string `json:"ext_ip"`
 	// InternalIP is the sensor's internal IP address
@@ -91,8 +80,9 @@ type Sensor struct {
 	ShouldSeal bool `json:"should_seal"`
 	// KernelAvailable indicates if kernel mode is available
 	KernelAvailable bool `json:"is_kernel_available"`
-	// ExternalPlatform is the platform ID from external sources
-	ExternalPlatform int64 `json:"ext_plat"`
+	// ExternalPlatform is the platform of the source of an adapter's
+	// data, e.g. Windows for Windows event logs; 0 if none
+	ExternalPlatform Platform `json:"ext_plat"`
 	// InstallerVersion is the version of the installer used
 	InstallerVersion string `json:"installer_version,omitempty"`
 }
@@ -100,6 +90,8 @@ type Sensor struct {
 // SensorList represents a list of sensors response
 type SensorList struct {
 	Sensors []Sensor `json:"sensors"`
+	// ContinuationToken identifies the next page; empty on the last page
+	ContinuationToken string `json:"continuation_token,omitempty"`
 }
 
 // OnlineStatusResponse represents the online status response
@@ -113,53 +105,26 @@ type TaskResponse struct {
 	ID    string `json:"id,omitempty"`
 }
 
-// GetPlatformString returns a human-readable platform name based on
-// the sensor's PlatformID. It handles all known platform types and
-// returns "Unknown" with the hex value for unknown platforms.
+// GetPlatformString returns the display name of the sensor's platform,
+// e.g. "macOS", or "Unknown" with the hex value for unknown platforms.
 func (s *Sensor) GetPlatformString() string {
-	// Platform IDs from LimaCharlie
-	// Windows: 0x10000000 (268435456)
-	// macOS:   0x30000000 (805306368)
-	// Linux:   0x20000000 (536870912)
-	// Cloud:   0x90000000 (2415919104)
-	switch s.PlatformID {
-	case 268435456:
-		return "Windows"
-	case 805306368:
-		return "macOS"
-	case 536870912:
-		return "Linux"
-	case 2415919104:
-		return "Cloud"
-	default:
-		return fmt.Sprintf("Unknown (0x%x)", s.PlatformID)
-	}
+	return s.PlatformID.String()
 }
 
-// GetArchitectureString returns a human-readable architecture name
-// based on the sensor's Architecture value. It handles all known
-// architectures and returns "Unknown" with the value for unknown types.
+// GetArchitectureString returns the display name of the sensor's
+// architecture, e.g. "ARM64", or "Unknown" with the value for unknown
+// architectures.
 func (s *Sensor) GetArchitectureString() string {
-	// Architecture IDs from LimaCharlie
-	// x86: 1
-	// x64: 2
-	// ARM: 3
-	// ARM64: 4
-	// Cloud: 9
-	switch s.Architecture {
-	case 1:
-		return "x86"
-	case 2:
-		return "x64"
-	case 3:
-		return "ARM"
-	case 4:
-		return "ARM64"
-	case 9:
-		return "Cloud"
-	default:
-		return fmt.Sprintf("Unknown (%d)", s.Architecture)
+	return s.Architecture.String()
+}
+
+// GetExternalPlatformString returns the display name of the platform of
+// an adapter's data source, or "" if the sensor has none.
+func (s *Sensor) GetExternalPlatformString() string {
+	if s.ExternalPlatform == 0 {
+		return ""
 	}
+	return s.ExternalPlatform.String()
 }
 
 // GetLastSeenString returns the last seen time of the sensor.
@@ -171,6 +136,87 @@ func (s *Sensor) GetLastSeenString() string {
 	return s.LastSeen
 }
 
+// UnmarshalJSON decodes a sensor and parses its LastSeen and
+// EnrollmentTime into LastSeenAt and EnrolledAt. Times in an unknown
+// format are left zero rather than failing the decoding.
+func (s *Sensor) UnmarshalJSON(data []byte) error {
+	type sensorJSON Sensor
+	if err := json.Unmarshal(data, (*sensorJSON)(s)); err != nil {
+		return err
+	}
+	s.LastSeenAt, _ = parseSensorTime(s.LastSeen)
+	s.EnrolledAt, _ = parseSensorTime(s.EnrollmentTime)
+	return nil
+}
+
+// MarshalJSON encodes a sensor with the display names of its platform,
+// architecture and external platform next to their IDs, so that JSON
+// output shows the same names as text and CSV output.
+func (s Sensor) MarshalJSON() ([]byte, error) {
+	type sensorJSON Sensor
+	return json.Marshal(struct {
+		sensorJSON
+		PlatformName         string `json:"platform"`
+		ArchitectureName     string `json:"architecture"`
+		ExternalPlatformName string `json:"ext_platform,omitempty"`
+	}{
+		sensorJSON:           sensorJSON(s),
+		PlatformName:         s.GetPlatformString(),
+		ArchitectureName:     s.GetArchitectureString(),
+		ExternalPlatformName: s.GetExternalPlatformString(),
+	})
+}
+
+// LastSeenTime returns the last seen time of the sensor.
+//
+// Returns:
+//   - time.Time: The last contact, in UTC
+//   - bool: False if the sensor has never been seen or the time is not
+//     in a known format
+func (s *Sensor) LastSeenTime() (time.Time, bool) {
+	if !s.LastSeenAt.IsZero() {
+		return s.LastSeenAt, true
+	}
+	return parseSensorTime(s.LastSeen)
+}
+
+// EnrolledTime returns the enrollment time of the sensor.
+//
+// Returns:
+//   - time.Time: The enrollment time, in UTC
+//   - bool: False if the time is missing or not in a known format
+func (s *Sensor) EnrolledTime() (time.Time, bool) {
+	if !s.EnrolledAt.IsZero() {
+		return s.EnrolledAt, true
+	}
+	return parseSensorTime(s.EnrollmentTime)
+}
+
+// sensorTimeLayouts are the formats LimaCharlie uses for sensor times.
+// Times without a zone are UTC.
+var sensorTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.999999",
+	time.RFC3339Nano,
+}
+
+// parseSensorTime parses a sensor time in any of sensorTimeLayouts or as
+// Unix seconds.
+func parseSensorTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range sensorTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Unix(0, int64(secs*float64(time.Second))).UTC(), true
+	}
+	return time.Time{}, false
+}
+
 // GetEnrollmentTimeString returns the enrollment time of the sensor.
 // Returns "Never" if the sensor has not been enrolled.
 func (s *Sensor) GetEnrollmentTimeString() string {
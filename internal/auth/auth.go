@@ -8,37 +8,81 @@
 // - API key validation
 // - Authorization header generation
 //
+// Three kinds of credentials are supported:
+// - Organization API keys (OID + key), via NewCredentials
+// - User API keys (user ID + key) spanning organizations, via NewUserCredentials
+// - Pre-issued JWTs, via NewJWTCredentials
+//
 // Example usage:
 //
 //	creds := auth.NewCredentials(orgID, apiKey)
-//	if err := creds.ValidateCredentials(); err != nil {
+//	if err := creds.ValidateCredentials(ctx); err != nil {
 //	    log.Fatal("Invalid credentials:", err)
 //	}
-//	authHeader := creds.GetAuthHeader()
+//	authHeader, err := creds.GetAuthHeader(ctx)
 package auth
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
+// DefaultJWTEndpoint is the LimaCharlie endpoint that exchanges an API key
+// for a JWT token.
+const DefaultJWTEndpoint = "https://jwt.limacharlie.io"
+
+// refreshMargin is how long before its expiry a cached JWT is replaced,
+// so that a token does not expire while a request is in flight. Tokens
+// with a short lifetime are refreshed after half of it instead.
+const refreshMargin = 2 * time.Minute
+
+// ErrInvalidCredentials is returned when the JWT endpoint rejects the
+// organization ID or API key.
+var ErrInvalidCredentials = errors.New("credentials rejected by the JWT endpoint")
+
+// ErrJWTExpired is returned when a pre-issued JWT has expired or was
+// rejected by the API. Such a token cannot be refreshed.
+var ErrJWTExpired = errors.New("pre-issued JWT expired or rejected")
+
 // JWTResponse represents the response from the JWT endpoint
 type JWTResponse struct {
 	JWT string `json:"jwt"`
 }
 
 // Credentials represents authentication credentials for LimaCharlie.
-// It contains the organization ID and API key required for API access.
+// It contains the organization ID and either an API key, exchanged for
+// JWTs as needed, or a pre-issued JWT.
+// Credentials are safe for concurrent use; concurrent callers share a
+// single JWT refresh.
 type Credentials struct {
-	// OID is the organization identifier
+	// OID is the organization identifier. It may be empty for user
+	// credentials that are not bound to one organization.
 	OID string
+	// UID is the user identifier of a user API key, empty for an
+	// organization API key
+	UID string
 	// apiKey is the API key for authentication (kept private)
 	apiKey string
-	jwt    string // cached JWT token
+	// preIssued is set when jwt was supplied by the caller and cannot
+	// be refreshed
+	preIssued bool
+
+	mu           sync.Mutex // guards the fields below
+	jwt          string     // cached JWT token
+	jwtExpiry    time.Time  // expiry of the cached token, zero if unknown
+	jwtRefreshAt time.Time  // when the cached token should be replaced
+
+	jwtEndpoint string       // endpoint used to obtain the JWT
+	httpClient  *http.Client // client used for JWT requests
+	userAgent   string       // User-Agent header for JWT requests
 }
 
 // NewCredentials creates a new Credentials instance with the provided
@@ -53,35 +97,147 @@ type Credentials struct {
 //   - *Credentials: A new credentials instance
 func NewCredentials(orgID, apiKey string) *Credentials {
 	return &Credentials{
-		OID:    orgID,
-		apiKey: apiKey,
+		OID:         orgID,
+		apiKey:      apiKey,
+		jwtEndpoint: DefaultJWTEndpoint,
+		httpClient:  http.DefaultClient,
+	}
+}
+
+// NewUserCredentials creates credentials for a user API key. A user key
+// can access every organization the user is a member of; the JWTs it
+// obtains are scoped to orgID, or to all of the user's organizations
+// when orgID is empty.
+//
+// Parameters:
+//   - uid: The user identifier
+//   - apiKey: The user API key
+//   - orgID: The organization to scope tokens to, or "" for none
+//
+// Returns:
+//   - *Credentials: A new credentials instance
+func NewUserCredentials(uid, apiKey, orgID string) *Credentials {
+	c := NewCredentials(orgID, apiKey)
+	c.UID = uid
+	return c
+}
+
+// NewJWTCredentials creates credentials from a JWT issued elsewhere, for
+// example by "limacharlie login". The token is used as is and cannot be
+// refreshed; once it expires, requests fail with ErrJWTExpired. When
+// orgID is empty it is taken from the token's oid claim if that names a
+// single organization.
+//
+// Parameters:
+//   - orgID: The organization identifier, or "" to read it from the token
+//   - token: The encoded JWT
+//
+// Returns:
+//   - *Credentials: A new credentials instance
+func NewJWTCredentials(orgID, token string) *Credentials {
+	c := NewCredentials(orgID, "")
+	c.preIssued = true
+	c.jwt = token
+	if claims, err := decodeClaims(token); err == nil {
+		if claims.Exp != 0 {
+			c.jwtExpiry = time.Unix(int64(claims.Exp), 0)
+		}
+		if c.OID == "" {
+			c.OID = claims.singleOID()
+		}
+	}
+	return c
+}
+
+// PreIssued reports whether the credentials hold a caller-supplied JWT
+// rather than an API key.
+func (c *Credentials) PreIssued() bool {
+	return c.preIssued
+}
+
+// SetJWTEndpoint overrides the endpoint used to obtain JWT tokens.
+// Any cached token is discarded since it was issued by another endpoint.
+func (c *Credentials) SetJWTEndpoint(endpoint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if endpoint == "" || endpoint == c.jwtEndpoint || c.preIssued {
+		return
+	}
+	c.jwtEndpoint = endpoint
+	c.jwt = ""
+	c.jwtExpiry = time.Time{}
+	c.jwtRefreshAt = time.Time{}
+}
+
+// SetHTTPClient sets the HTTP client used for JWT requests so that they
+// share the connection pool of the API client.
+func (c *Credentials) SetHTTPClient(httpClient *http.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if httpClient != nil {
+		c.httpClient = httpClient
 	}
 }
 
-// GetJWT obtains a JWT token from LimaCharlie
-func (c *Credentials) GetJWT() (string, error) {
-	// Return cached JWT if available
-	if c.jwt != "" {
+// SetUserAgent sets the User-Agent header sent with JWT requests.
+func (c *Credentials) SetUserAgent(userAgent string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.userAgent = userAgent
+}
+
+// GetJWT obtains a JWT token from LimaCharlie. A cached token is returned
+// until shortly before its expiry; after that a new one is requested.
+// The context bounds the request to the JWT endpoint.
+func (c *Credentials) GetJWT(ctx context.Context) (string, error) {
+	// Holding the lock for the whole refresh makes concurrent callers
+	// wait for, and then share, the same new token
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A pre-issued token is used until it expires or is rejected
+	if c.preIssued {
+		if c.jwt == "" || (!c.jwtExpiry.IsZero() && !time.Now().Before(c.jwtExpiry)) {
+			return "", ErrJWTExpired
+		}
+		return c.jwt, nil
+	}
+
+	// Return cached JWT if it is still fresh
+	if c.jwt != "" && (c.jwtRefreshAt.IsZero() || time.Now().Before(c.jwtRefreshAt)) {
 		return c.jwt, nil
 	}
 
 	// Build URL and form data
 	form := url.Values{}
-	form.Add("oid", c.OID)
+	if c.UID != "" {
+		// User keys request a token scoped to one organization, or
+		// to all of them with "-"
+		form.Add("uid", c.UID)
+		if c.OID != "" {
+			form.Add("oid", c.OID)
+		} else {
+			form.Add("oid", "-")
+		}
+	} else {
+		form.Add("oid", c.OID)
+	}
 	form.Add("secret", c.apiKey)
 
 	// Create request
-	req, err := http.NewRequest("POST", "https://jwt.limacharlie.io", strings.NewReader(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.jwtEndpoint, strings.NewReader(form.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("error creating request: %w", err)
 	}
 
 	// Set content type
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
 
 	// Make request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("error making request: %w", err)
 	}
@@ -93,6 +249,9 @@ func (c *Credentials) GetJWT() (string, error) {
 		return "", fmt.Errorf("error reading response body: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w: status %d: %s", ErrInvalidCredentials, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("request failed with status: %d, body: %s", resp.StatusCode, string(body))
 	}
@@ -102,19 +261,55 @@ func (c *Credentials) GetJWT() (string, error) {
 		return "", fmt.Errorf("error decoding response: %w", err)
 	}
 
-	// Cache the JWT token
+	// Cache the JWT token along with its expiry. Tokens without a
+	// readable exp claim are kept until the API rejects them.
 	c.jwt = jwtResp.JWT
+	c.jwtExpiry, _ = jwtExpiry(c.jwt)
+	c.jwtRefreshAt = time.Time{}
+	if !c.jwtExpiry.IsZero() {
+		margin := refreshMargin
+		if lifetime := time.Until(c.jwtExpiry); lifetime/2 < margin {
+			margin = lifetime / 2
+		}
+		c.jwtRefreshAt = c.jwtExpiry.Add(-margin)
+	}
 	return c.jwt, nil
 }
 
+// InvalidateJWT discards the cached token if it is still the given one,
+// forcing the next GetJWT call to request a new token. Callers pass the
+// token that was rejected so that a token already refreshed by another
+// goroutine is not thrown away. A rejected pre-issued token makes later
+// GetJWT calls fail with ErrJWTExpired.
+func (c *Credentials) InvalidateJWT(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.jwt == token {
+		c.jwt = ""
+		c.jwtExpiry = time.Time{}
+		c.jwtRefreshAt = time.Time{}
+	}
+}
+
+// JWTExpiry returns the expiry of the cached token, or the zero time if
+// no token is cached or its expiry is unknown.
+func (c *Credentials) JWTExpiry() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.jwtExpiry
+}
+
 // GetAuthHeader generates the Authorization header value for API requests.
 // The header format follows LimaCharlie's requirements for API authentication.
 //
+// Parameters:
+//   - ctx: Context controlling cancellation of the JWT request
+//
 // Returns:
 //   - string: The complete Authorization header value
 //   - error: Any error that occurred while getting the JWT token
-func (c *Credentials) GetAuthHeader() (string, error) {
-	jwt, err := c.GetJWT()
+func (c *Credentials) GetAuthHeader(ctx context.Context) (string, error) {
+	jwt, err := c.GetJWT(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get JWT token: %w", err)
 	}
@@ -133,16 +328,17 @@ func (c *Credentials) GetAPIKey() string {
 // ValidateCredentials checks if the credentials are valid by attempting to obtain a JWT token.
 // It returns an error if the credentials are invalid or if there was an error communicating
 // with the authentication service.
-func (c *Credentials) ValidateCredentials() error {
-	if c.OID == "" {
+// User API keys and pre-issued JWTs do not need an organization ID.
+func (c *Credentials) ValidateCredentials(ctx context.Context) error {
+	if c.OID == "" && c.UID == "" && !c.preIssued {
 		return fmt.Errorf("organization ID is required")
 	}
-	if c.apiKey == "" {
+	if c.apiKey == "" && !c.preIssued {
 		return fmt.Errorf("API key is required")
 	}
 
 	// Try to get a JWT token to validate credentials
-	_, err := c.GetJWT()
+	_, err := c.GetJWT(ctx)
 	if err != nil {
 		return fmt.Errorf("invalid credentials: %w", err)
 	}
@@ -156,5 +352,11 @@ func (c *Credentials) ValidateCredentials() error {
 // Returns:
 //   - string: A string representation with masked API key
 func (c *Credentials) String() string {
+	switch {
+	case c.preIssued:
+		return fmt.Sprintf("Credentials{OID: %s, JWT: ****}", c.OID)
+	case c.UID != "":
+		return fmt.Sprintf("Credentials{UID: %s, OID: %s, APIKey: ****}", c.UID, c.OID)
+	}
 	return fmt.Sprintf("Credentials{OID: %s, APIKey: ****}", c.OID)
 }
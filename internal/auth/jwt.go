@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jwtClaims holds the registered claims this package reads from a token.
+type jwtClaims struct {
+	// Exp is the expiry as seconds since the Unix epoch
+	Exp float64 `json:"exp"`
+	// OID is the organization the token is scoped to: a single OID, or
+	// a list for user tokens spanning several organizations
+	OID interface{} `json:"oid"`
+}
+
+// singleOID returns the OID claim if the token is scoped to exactly one
+// organization, and "" otherwise.
+func (c *jwtClaims) singleOID() string {
+	switch v := c.OID.(type) {
+	case string:
+		if v != "-" {
+			return v
+		}
+	case []interface{}:
+		if len(v) == 1 {
+			s, _ := v[0].(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// jwtExpiry decodes the exp claim of a JWT without verifying its
+// signature. The signature is checked by the API; the expiry is only
+// used to decide when to request a new token.
+//
+// Parameters:
+//   - token: The encoded JWT
+//
+// Returns:
+//   - time.Time: The token's expiry
+//   - error: An error if the token or its claims cannot be decoded
+func jwtExpiry(token string) (time.Time, error) {
+	claims, err := decodeClaims(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("JWT has no exp claim")
+	}
+
+	return time.Unix(int64(claims.Exp), 0), nil
+}
+
+// decodeClaims decodes the payload of a JWT without verifying its
+// signature.
+func decodeClaims(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed JWT: expected 3 segments, got %d", len(parts))
+	}
+
+	// JWT segments are unpadded base64url, but tolerate padding
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding JWT payload: %w", err)
+	}
+
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("error decoding JWT claims: %w", err)
+	}
+	return &claims, nil
+}
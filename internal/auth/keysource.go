@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// The functions in this file obtain an API key from somewhere other than
+// the command line, where it would be left in shell history and process
+// listings. The key they return is passed to NewCredentials as usual.
+
+// ErrInsecureKeyFile is returned when an API key file can be read by
+// every user on the system.
+var ErrInsecureKeyFile = errors.New("API key file is readable by all users")
+
+// ReadAPIKeyFile reads an API key from a file, ignoring surrounding
+// whitespace. On systems with Unix permissions, files readable by all
+// users are refused; restrict them with chmod 600.
+//
+// Parameters:
+//   - path: Location of the key file
+//
+// Returns:
+//   - string: The API key
+//   - error: Any error reading the file, or ErrInsecureKeyFile
+func ReadAPIKeyFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening API key file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("error reading API key file: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("API key file %s is a directory", path)
+	}
+	// Windows does not report meaningful permission bits
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o004 != 0 {
+		return "", fmt.Errorf("%w: %s has mode %04o, run chmod 600 on it", ErrInsecureKeyFile, path, info.Mode().Perm())
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(f); err != nil {
+		return "", fmt.Errorf("error reading API key file: %w", err)
+	}
+	key := strings.TrimSpace(buf.String())
+	if key == "" {
+		return "", fmt.Errorf("API key file %s is empty", path)
+	}
+	return key, nil
+}
+
+// APIKeyFromCommand runs a credential helper, such as "pass show lc/prod"
+// or a vault CLI, through the system shell and returns its standard
+// output as the API key. The helper shares the terminal so that it can
+// ask for a passphrase.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the helper
+//   - command: The shell command to run
+//
+// Returns:
+//   - string: The API key
+//   - error: An error if the helper fails or prints nothing
+func APIKeyFromCommand(ctx context.Context, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("API key command failed: %w", err)
+	}
+	key := strings.TrimSpace(stdout.String())
+	if key == "" {
+		return "", fmt.Errorf("API key command printed nothing")
+	}
+	return key, nil
+}
+
+// CanPromptAPIKey reports whether an API key can be read interactively,
+// that is whether standard input is a terminal.
+func CanPromptAPIKey() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// PromptAPIKey asks for an API key on the terminal without echoing it.
+// The prompt is written to standard error so that it never mixes with
+// command output.
+//
+// Parameters:
+//   - prompt: The text shown before the input
+//
+// Returns:
+//   - string: The API key
+//   - error: An error if standard input is not a terminal or nothing was entered
+func PromptAPIKey(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("cannot prompt for the API key: standard input is not a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	input, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error reading API key: %w", err)
+	}
+	key := strings.TrimSpace(string(input))
+	if key == "" {
+		return "", fmt.Errorf("no API key entered")
+	}
+	return key, nil
+}
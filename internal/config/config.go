@@ -0,0 +1,268 @@
+// Package config provides named profiles for the LC_utils tools.
+// It handles loading and saving the config file and resolving the
+// credentials and defaults of a profile.
+//
+// The config file lives at ~/.config/lc-sensors/config.yaml (or under
+// $XDG_CONFIG_HOME) and looks like:
+//
+//	current_profile: prod
+//	profiles:
+//	  prod:
+//	    oid: 11111111-2222-3333-4444-555555555555
+//	    api_key_env: LC_PROD_KEY
+//	    output: json
+//	    concurrency: 20
+//	  lab:
+//	    oid: 66666666-7777-8888-9999-000000000000
+//	    api_key: "..."
+//	    endpoint: http://127.0.0.1:8080
+//	  analyst:
+//	    uid: aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee
+//	    api_key_command: pass show limacharlie/user-key
+//
+// A profile takes its API key from at most one of api_key, api_key_env,
+// api_key_file or api_key_command.
+//
+// Example usage:
+//
+//	cfg, err := config.Load(config.DefaultPath())
+//	profile, err := cfg.Profile("prod")
+//	apiKey, err := profile.ResolveAPIKey(ctx)
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"LC_utils/internal/auth"
+)
+
+// ErrProfileNotFound is returned when a named profile does not exist.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// Profile holds the credentials and defaults for one organization.
+// Empty fields are not set by the profile.
+type Profile struct {
+	// OID is the organization ID
+	OID string `yaml:"oid,omitempty"`
+	// UID is the user ID when the API key is a user API key
+	UID string `yaml:"uid,omitempty"`
+	// APIKey is the API key stored in the config file
+	APIKey string `yaml:"api_key,omitempty"`
+	// APIKeyEnv names an environment variable holding the API key
+	APIKeyEnv string `yaml:"api_key_env,omitempty"`
+	// APIKeyFile is a file holding the API key
+	APIKeyFile string `yaml:"api_key_file,omitempty"`
+	// APIKeyCommand is a shell command that prints the API key
+	APIKeyCommand string `yaml:"api_key_command,omitempty"`
+	// Endpoint overrides the API base URL
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// JWTEndpoint overrides the JWT endpoint
+	JWTEndpoint string `yaml:"jwt_endpoint,omitempty"`
+	// Theme is the default visual theme
+	Theme string `yaml:"theme,omitempty"`
+	// Output is the default list output format
+	Output string `yaml:"output,omitempty"`
+	// Concurrency is the default number of parallel workers (0 = unset)
+	Concurrency int `yaml:"concurrency,omitempty"`
+}
+
+// Config is the contents of the config file.
+type Config struct {
+	// CurrentProfile is the profile used when none is selected
+	CurrentProfile string `yaml:"current_profile,omitempty"`
+	// Profiles maps profile names to profiles
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// DefaultPath returns the config file location. LC_SENSORS_CONFIG
+// overrides it; otherwise it is lc-sensors/config.yaml under
+// $XDG_CONFIG_HOME or ~/.config.
+func DefaultPath() string {
+	if path := os.Getenv("LC_SENSORS_CONFIG"); path != "" {
+		return path
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "lc-sensors", "config.yaml")
+}
+
+// Load reads the config file at path. A missing file yields an empty
+// config rather than an error.
+//
+// Parameters:
+//   - path: Location of the config file
+//
+// Returns:
+//   - *Config: The parsed configuration
+//   - error: Any error reading or parsing the file
+func Load(path string) (*Config, error) {
+	cfg := &Config{Profiles: map[string]*Profile{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	cfg, err = decodeYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	for name, profile := range cfg.Profiles {
+		if err := validateProfile(profile); err != nil {
+			return nil, fmt.Errorf("error parsing %s: profile %q: %w", path, name, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// validateProfile checks the settings of a profile read from the file.
+func validateProfile(p *Profile) error {
+	if p.Concurrency < 0 {
+		return fmt.Errorf("concurrency must be a positive integer, got %d", p.Concurrency)
+	}
+	sources := 0
+	for _, source := range []string{p.APIKey, p.APIKeyEnv, p.APIKeyFile, p.APIKeyCommand} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of api_key, api_key_env, api_key_file and api_key_command may be set")
+	}
+	return nil
+}
+
+// Save writes the config to path, creating its directory. The file is
+// only readable by the owner because profiles may hold API keys.
+func (c *Config) Save(path string) error {
+	data, err := encodeYAML(c)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString("# lc-sensors configuration; manage with `lc-sensors profile`\n")
+	sb.Write(data)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error creating config directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed write never leaves a
+	// truncated config behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(sb.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	return nil
+}
+
+// Profile returns the named profile.
+func (c *Config) Profile(name string) (*Profile, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+	}
+	return p, nil
+}
+
+// ValidateProfileName checks that a profile name can be written to the
+// config file and given to --profile and --orgs: letters, digits, dots,
+// dashes and underscores.
+func ValidateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name must not be empty")
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return fmt.Errorf("invalid profile name %q: use letters, digits, dots, dashes and underscores", name)
+		}
+	}
+	return nil
+}
+
+// Names returns the profile names in sorted order.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveAPIKey returns the profile's API key, reading it from the
+// environment variable, key file or credential helper the profile names.
+// The context bounds a credential helper.
+func (p *Profile) ResolveAPIKey(ctx context.Context) (string, error) {
+	switch {
+	case p.APIKeyEnv != "":
+		key := os.Getenv(p.APIKeyEnv)
+		if key == "" {
+			return "", fmt.Errorf("environment variable %s named by api_key_env is not set", p.APIKeyEnv)
+		}
+		return key, nil
+	case p.APIKeyFile != "":
+		return auth.ReadAPIKeyFile(p.APIKeyFile)
+	case p.APIKeyCommand != "":
+		return auth.APIKeyFromCommand(ctx, p.APIKeyCommand)
+	}
+	return p.APIKey, nil
+}
+
+// HasAPIKey reports whether the profile names a source for its API key.
+func (p *Profile) HasAPIKey() bool {
+	return p.APIKey != "" || p.APIKeyEnv != "" || p.APIKeyFile != "" || p.APIKeyCommand != ""
+}
+
+// KeySource describes where the profile's API key comes from without
+// revealing it.
+func (p *Profile) KeySource() string {
+	source := "none"
+	switch {
+	case p.APIKeyEnv != "":
+		source = "env:" + p.APIKeyEnv
+	case p.APIKeyFile != "":
+		source = "file:" + p.APIKeyFile
+	case p.APIKeyCommand != "":
+		source = "command"
+	case p.APIKey != "":
+		source = "config file"
+	}
+	if p.UID != "" {
+		source += " (user key)"
+	}
+	return source
+}
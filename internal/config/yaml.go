@@ -0,0 +1,54 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The config file is YAML, read and written by Config and Profile's yaml
+// tags. Unknown settings are refused so that a misspelt key is reported
+// rather than silently ignored.
+
+// decodeYAML parses the config file.
+//
+// Parameters:
+//   - data: The file contents
+//
+// Returns:
+//   - *Config: The configuration; profiles left empty are set
+//   - error: A syntax error or unknown setting, with its line number
+func decodeYAML(data []byte) (*Config, error) {
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*Profile{}
+	}
+	for name, p := range cfg.Profiles {
+		if p == nil {
+			cfg.Profiles[name] = &Profile{}
+		}
+	}
+	return cfg, nil
+}
+
+// encodeYAML formats the config file, indenting mappings by two spaces.
+func encodeYAML(cfg *Config) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,182 @@
+// Package fanout provides a bounded worker pool for running the same
+// operation against many items, such as tasking every sensor in a
+// selection.
+//
+// The pool provides:
+// - A fixed number of concurrent workers
+// - A shared requests-per-second rate limit
+// - Results collected in input order regardless of completion order
+// - Cancellation through context, with unstarted items reported as skipped
+//
+// Example usage:
+//
+//	pool := fanout.New(10, 5)
+//	results := pool.Run(ctx, len(sensors), func(ctx context.Context, i int) error {
+//	    return tagSensor(ctx, sensors[i])
+//	}, nil)
+package fanout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of a single work item.
+type Result struct {
+	// Index is the position of the item in the input
+	Index int
+	// Started reports whether the work function ran for this item
+	Started bool
+	// Err is the error returned by the work function, or the context
+	// error if the item was never started
+	Err error
+	// Duration is how long the work function ran
+	Duration time.Duration
+}
+
+// Pool runs work items with bounded concurrency and an optional rate
+// limit. A Pool may be reused for several runs; the rate limit is shared
+// between them.
+type Pool struct {
+	concurrency int
+	limiter     *Limiter
+}
+
+// New creates a Pool.
+//
+// Parameters:
+//   - concurrency: Maximum number of items processed at once (minimum 1)
+//   - rate: Maximum number of requests started per second (0 = unlimited)
+//
+// Returns:
+//   - *Pool: A new worker pool
+func New(concurrency int, rate float64) *Pool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Pool{
+		concurrency: concurrency,
+		limiter:     NewLimiter(rate),
+	}
+}
+
+// Wait blocks until the pool's rate limit allows another request. Run
+// calls it before each item; work functions that issue more than one
+// request per item should call it before each additional request.
+func (p *Pool) Wait(ctx context.Context) error {
+	return p.limiter.Wait(ctx)
+}
+
+// Run calls fn for every index in [0, n) and returns one Result per
+// index, in input order. If ctx is cancelled, items that have not been
+// started are skipped and reported with the context error.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the whole run
+//   - n: Number of items
+//   - fn: Work function called with the item index
+//   - onDone: Optional callback invoked for each result as it completes;
+//     calls are serialized so it may print without extra locking
+//
+// Returns:
+//   - []Result: Results ordered by item index
+func (p *Pool) Run(ctx context.Context, n int, fn func(ctx context.Context, i int) error, onDone func(Result)) []Result {
+	results := make([]Result, n)
+	indexes := make(chan int)
+
+	var mu sync.Mutex
+	finish := func(r Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[r.Index] = r
+		if onDone != nil {
+			onDone(r)
+		}
+	}
+
+	var wg sync.WaitGroup
+	workers := p.concurrency
+	if workers > n {
+		workers = n
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := p.Wait(ctx); err != nil {
+					finish(Result{Index: i, Err: err})
+					continue
+				}
+				start := time.Now()
+				err := fn(ctx, i)
+				finish(Result{Index: i, Started: true, Err: err, Duration: time.Since(start)})
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			finish(Result{Index: i, Err: ctx.Err()})
+			continue
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			finish(Result{Index: i, Err: ctx.Err()})
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// Limiter spaces out requests so that no more than a fixed number start
+// per second. A nil Limiter does not limit.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter returns a Limiter allowing rate requests per second, or nil
+// if rate is not positive.
+func NewLimiter(rate float64) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// Wait blocks until the next request may start or ctx is cancelled.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	// Reserve the next slot
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
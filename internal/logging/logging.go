@@ -0,0 +1,153 @@
+// Package logging provides leveled, structured logging for the LC_utils
+// tools on top of log/slog.
+//
+// The package provides:
+// - Logger construction from --log-level and --log-format style options
+// - Redaction of secrets (API keys, JWTs, passwords) in log attributes
+// - An HTTP transport that traces requests and responses on demand
+//
+// Logs are meant for stderr so that they never mix with command output
+// such as JSON or CSV written to stdout.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secret values in log output.
+const Redacted = "[REDACTED]"
+
+// LevelTrace is below debug and is used for HTTP wire traces.
+const LevelTrace = slog.LevelDebug - 4
+
+// Options configures a logger.
+type Options struct {
+	// Level is one of debug, info, warn or error
+	Level string
+	// Format is text or json
+	Format string
+	// ShowSecrets disables redaction of secret values
+	ShowSecrets bool
+	// HTTPTrace lowers the level to LevelTrace so wire traces are logged
+	HTTPTrace bool
+}
+
+// New creates a logger writing to w.
+//
+// Parameters:
+//   - w: Destination of the log records, normally os.Stderr
+//   - opts: Level, format and redaction settings
+//
+// Returns:
+//   - *slog.Logger: The configured logger
+//   - error: An error if the level or format is unknown
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.HTTPTrace {
+		level = LevelTrace
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.LevelKey && len(groups) == 0 {
+				if l, ok := a.Value.Any().(slog.Level); ok && l == LevelTrace {
+					return slog.String(a.Key, "TRACE")
+				}
+			}
+			if opts.ShowSecrets {
+				return a
+			}
+			return redactAttr(a)
+		},
+	}
+
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected text or json)", opts.Format)
+	}
+}
+
+// ParseLevel converts a level name to a slog.Level.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", name)
+	}
+}
+
+// Discard returns a logger that drops every record.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// secretKeys lists attribute keys, compared case-insensitively with "-"
+// and "_" removed, whose values are always redacted.
+var secretKeys = map[string]bool{
+	"apikey":        true,
+	"secret":        true,
+	"jwt":           true,
+	"token":         true,
+	"authorization": true,
+	"password":      true,
+	"certpass":      true,
+	"certpassword":  true,
+}
+
+// IsSecretKey reports whether values stored under key must be redacted.
+func IsSecretKey(key string) bool {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(key))
+	return secretKeys[normalized]
+}
+
+// redactAttr hides secret values in a log attribute.
+func redactAttr(a slog.Attr) slog.Attr {
+	if IsSecretKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	if a.Value.Kind() == slog.KindString {
+		if s := a.Value.String(); s != RedactString(s) {
+			return slog.String(a.Key, RedactString(s))
+		}
+	}
+	return a
+}
+
+var (
+	// bearerPattern matches bearer tokens in header values
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-_.=]+`)
+	// formSecretPattern matches secret fields in URL-encoded forms and queries
+	formSecretPattern = regexp.MustCompile(`(?i)\b(secret|api_key|apikey|jwt|token|password|cert_pass)=[^&\s]*`)
+	// jsonSecretPattern matches secret fields in JSON documents, including
+	// pre-signed URLs whose signature grants access
+	jsonSecretPattern = regexp.MustCompile(`(?i)"(secret|api_key|apikey|jwt|token|password|cert_pass|put_url)"\s*:\s*"[^"]*"`)
+)
+
+// RedactString hides bearer tokens and secret fields embedded in s, such
+// as an Authorization header, a form body or a JSON response.
+func RedactString(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "${1}"+Redacted)
+	s = formSecretPattern.ReplaceAllString(s, "${1}="+Redacted)
+	s = jsonSecretPattern.ReplaceAllString(s, `"${1}":"`+Redacted+`"`)
+	return s
+}
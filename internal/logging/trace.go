@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxTraceBody limits how much of each request and response body is logged.
+const maxTraceBody = 4 << 10
+
+// TraceTransport is an http.RoundTripper that logs every request and
+// response at LevelTrace, including headers and the start of the body.
+// Secrets are redacted unless ShowSecrets is set.
+type TraceTransport struct {
+	// Base performs the actual requests; http.DefaultTransport if nil
+	Base http.RoundTripper
+	// Logger receives the trace records
+	Logger *slog.Logger
+	// ShowSecrets disables redaction of headers and bodies
+	ShowSecrets bool
+	// APIHosts are the hosts whose query strings are logged; the query
+	// strings of other hosts, such as the signature of a pre-signed
+	// upload URL, are redacted
+	APIHosts []string
+}
+
+// RoundTrip logs the request, performs it with the base transport and
+// logs the response.
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !t.Logger.Enabled(req.Context(), LevelTrace) {
+		return base.RoundTrip(req)
+	}
+
+	t.Logger.Log(req.Context(), LevelTrace, "http request",
+		slog.String("method", req.Method),
+		slog.String("url", t.redactURL(req.URL)),
+		slog.String("headers", t.formatHeaders(req.Header)),
+		slog.String("body", t.requestBody(req)),
+	)
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		t.Logger.Log(context.Background(), LevelTrace, "http error",
+			slog.String("method", req.Method),
+			slog.String("url", t.redactURL(req.URL)),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	t.Logger.Log(req.Context(), LevelTrace, "http response",
+		slog.String("method", req.Method),
+		slog.String("url", t.redactURL(req.URL)),
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("headers", t.formatHeaders(resp.Header)),
+		slog.String("body", t.redact(peekResponseBody(resp))),
+	)
+	return resp, nil
+}
+
+// redact applies RedactString unless secrets are shown.
+func (t *TraceTransport) redact(s string) string {
+	if t.ShowSecrets {
+		return s
+	}
+	return RedactString(s)
+}
+
+// redactURL formats a request URL, hiding the query string of hosts
+// other than the API's, whose parameters may sign the request.
+func (t *TraceTransport) redactURL(u *url.URL) string {
+	if t.ShowSecrets {
+		return u.String()
+	}
+	if u.RawQuery != "" && !t.isAPIHost(u.Host) {
+		hidden := *u
+		hidden.RawQuery = Redacted
+		return RedactString(hidden.String())
+	}
+	return RedactString(u.String())
+}
+
+// isAPIHost reports whether host is one of APIHosts, ignoring case.
+func (t *TraceTransport) isAPIHost(host string) bool {
+	for _, h := range t.APIHosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestBody returns the start of the request body for the trace.
+// Binary bodies, such as uploaded payloads, are only described.
+func (t *TraceTransport) requestBody(req *http.Request) string {
+	if req.Body == nil {
+		return ""
+	}
+	if mediaType, _, _ := strings.Cut(req.Header.Get("Content-Type"), ";"); strings.EqualFold(strings.TrimSpace(mediaType), "application/octet-stream") {
+		return fmt.Sprintf("(%d bytes of binary data)", req.ContentLength)
+	}
+	return t.redact(peekRequestBody(req))
+}
+
+// formatHeaders renders headers on one line, hiding secret values.
+func (t *TraceTransport) formatHeaders(h http.Header) string {
+	var parts []string
+	for name, values := range h {
+		value := strings.Join(values, ",")
+		if !t.ShowSecrets && IsSecretKey(name) {
+			value = Redacted
+		}
+		parts = append(parts, name+": "+value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// peekRequestBody returns the start of the request body without
+// consuming it. Bodies that cannot be re-read are not logged.
+func peekRequestBody(req *http.Request) string {
+	if req.Body == nil || req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(io.LimitReader(body, maxTraceBody))
+	return string(data)
+}
+
+// peekResponseBody returns the start of the response body and replaces
+// the body so the caller still reads it in full.
+func peekResponseBody(resp *http.Response) string {
+	if resp.Body == nil {
+		return ""
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxTraceBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	return string(data)
+}
@@ -0,0 +1,95 @@
+package match
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// AddrSet is a set of IP addresses given as CIDRs, ranges and single
+// addresses, IPv4 or IPv6. It is safe for concurrent use.
+type AddrSet struct {
+	source   string
+	prefixes []netip.Prefix
+	ranges   [][2]netip.Addr
+}
+
+// ParseAddrSet parses a comma-separated list of CIDRs ("10.0.0.0/8",
+// "fd00::/8"), ranges ("10.0.0.10-10.0.0.50") and addresses.
+//
+// Parameters:
+//   - spec: The list to parse
+//
+// Returns:
+//   - *AddrSet: The parsed set
+//   - error: Any invalid item, or an empty list
+func ParseAddrSet(spec string) (*AddrSet, error) {
+	set := &AddrSet{source: spec}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		switch {
+		case strings.Contains(item, "/"):
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", item)
+			}
+			set.prefixes = append(set.prefixes, prefix.Masked())
+		case strings.Contains(item, "-"):
+			first, last, _ := strings.Cut(item, "-")
+			from, err1 := parseAddr(first)
+			to, err2 := parseAddr(last)
+			if err1 != nil || err2 != nil || from.Is4() != to.Is4() || to.Less(from) {
+				return nil, fmt.Errorf("invalid range %q, use e.g. 10.0.0.10-10.0.0.50", item)
+			}
+			set.ranges = append(set.ranges, [2]netip.Addr{from, to})
+		default:
+			addr, err := parseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP address %q", item)
+			}
+			set.prefixes = append(set.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	if len(set.prefixes) == 0 && len(set.ranges) == 0 {
+		return nil, fmt.Errorf("empty address list %q", spec)
+	}
+	return set, nil
+}
+
+// Contains reports whether an address, given as text, is in the set.
+// Invalid or empty addresses are never in the set.
+func (s *AddrSet) Contains(ip string) bool {
+	addr, err := parseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, p := range s.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	for _, r := range s.ranges {
+		if r[0].Is4() == addr.Is4() && !addr.Less(r[0]) && !r[1].Less(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the list as it was given.
+func (s *AddrSet) String() string {
+	return s.source
+}
+
+// parseAddr parses an address, treating IPv4-mapped IPv6 addresses as
+// IPv4 and dropping any IPv6 zone.
+func parseAddr(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap().WithZone(""), nil
+}
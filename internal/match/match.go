@@ -0,0 +1,186 @@
+// Package match provides the hostname and tag patterns and the address
+// sets used to filter sensors.
+//
+// Patterns are globs by default:
+// - * matches any run of characters and ? exactly one
+// - [abc], [a-z] and [!abc] match one character of a class
+// - \ escapes the next character, e.g. \* or \,
+// - The glob must match the whole value, ignoring case
+//
+// A filter may list several globs separated by commas and matches if
+// any of them does. In regex mode the filter is a single regular
+// expression, matched anywhere in the value unless anchored with ^ and $,
+// also ignoring case.
+//
+// Patterns are compiled once and can then be matched against any number
+// of values. Address sets (AddrSet) hold CIDRs, ranges and addresses of
+// either IP version.
+//
+// Example usage:
+//
+//	p, err := match.Compile("web-*,db-0?", false)
+//	if p.Match(sensor.Hostname) {
+//	    // ...
+//	}
+package match
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a compiled filter. It is safe for concurrent use.
+type Pattern struct {
+	source string
+	res    []*regexp.Regexp
+}
+
+// Compile compiles a filter.
+//
+// Parameters:
+//   - filter: Comma-separated globs, or a regular expression with regex set
+//   - regex: Whether filter is a regular expression
+//
+// Returns:
+//   - *Pattern: The compiled filter
+//   - error: Any error in the globs or regular expression
+func Compile(filter string, regex bool) (*Pattern, error) {
+	p := &Pattern{source: filter}
+	if regex {
+		re, err := regexp.Compile("(?i)" + filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", filter, err)
+		}
+		p.res = []*regexp.Regexp{re}
+		return p, nil
+	}
+
+	for _, glob := range splitGlobs(filter) {
+		re, err := Glob(glob)
+		if err != nil {
+			return nil, err
+		}
+		p.res = append(p.res, re)
+	}
+	if len(p.res) == 0 {
+		return nil, fmt.Errorf("empty pattern %q", filter)
+	}
+	return p, nil
+}
+
+// Match reports whether value matches any of the patterns.
+func (p *Pattern) Match(value string) bool {
+	for _, re := range p.res {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAny reports whether any of values matches, e.g. any of a
+// sensor's tags.
+func (p *Pattern) MatchAny(values []string) bool {
+	for _, v := range values {
+		if p.Match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the filter as it was given.
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// splitGlobs splits a filter at the commas that are not escaped or inside
+// a character class, dropping empty and surrounding whitespace.
+func splitGlobs(filter string) []string {
+	var globs []string
+	var current strings.Builder
+	inClass := false
+	flush := func() {
+		if glob := strings.TrimSpace(current.String()); glob != "" {
+			globs = append(globs, glob)
+		}
+		current.Reset()
+	}
+	for i := 0; i < len(filter); i++ {
+		c := filter[i]
+		switch {
+		case c == '\\' && i+1 < len(filter):
+			current.WriteByte(c)
+			i++
+			c = filter[i]
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == ',' && !inClass:
+			flush()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	flush()
+	return globs
+}
+
+// Glob converts a single glob into an anchored, case-insensitive regular
+// expression.
+//
+// Parameters:
+//   - glob: The glob, e.g. "web-??" or "db[0-9]*"
+//
+// Returns:
+//   - *regexp.Regexp: The equivalent regular expression
+//   - error: Any error in the glob, such as an unterminated class
+func Glob(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			// A ] right after the opening bracket is part of the class
+			end := i + 1
+			if end < len(glob) && (glob[end] == '!' || glob[end] == '^') {
+				end++
+			}
+			if end < len(glob) && glob[end] == ']' {
+				end++
+			}
+			for end < len(glob) && glob[end] != ']' {
+				end++
+			}
+			if end >= len(glob) {
+				return nil, fmt.Errorf("unterminated character class in %q", glob)
+			}
+			class := glob[i+1 : end]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q", glob)
+	}
+	return re, nil
+}
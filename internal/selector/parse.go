@@ -0,0 +1,377 @@
+package selector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"LC_utils/internal/match"
+)
+
+// tokenKind identifies the lexical class of a token.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+// token is one lexical element of an expression.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// is reports whether t is the given keyword, ignoring case.
+func (t token) is(keyword string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, keyword)
+}
+
+// wordBreaks are the characters that end a bare word.
+const wordBreaks = "()[],\"'=!<>"
+
+// lex splits an expression into tokens.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(expr) {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokLBracket, text: "[", pos: i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokRBracket, text: "]", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			text, n, err := lexString(expr[i:])
+			if err != nil {
+				return nil, &SyntaxError{Column: i + 1, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: i})
+			i += n
+		case strings.IndexByte("=!<>", c) >= 0:
+			op := string(c)
+			if i+1 < len(expr) && expr[i+1] == '=' {
+				op += "="
+			}
+			if op == "=" || op == "!" {
+				return nil, &SyntaxError{Column: i + 1, Msg: fmt.Sprintf("unknown operator %q, use == or !=", op)}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		default:
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t\n\r", rune(expr[i])) && strings.IndexByte(wordBreaks, expr[i]) < 0 {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokWord, text: expr[start:i], pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+// lexString reads a quoted string at the start of s. Double-quoted
+// strings support Go escapes; single-quoted strings are taken literally.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			if quote == '\'' {
+				return s[1:i], i + 1, nil
+			}
+			text, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string %s", s[:i+1])
+			}
+			return text, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// parser builds the expression tree by recursive descent:
+//
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" or ")" | comparison
+//	comparison = field [ operator value | "in" ( value | list ) ]
+//	list       = "[" value { "," value } "]"
+type parser struct {
+	tokens    []token
+	pos       int
+	parseTime func(string) (time.Time, error)
+	used      map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return &SyntaxError{Column: tok.pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	tok := p.peek()
+	switch {
+	case tok.is("not"):
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	case tok.kind == tokLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "expected ) but found %s", closing)
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	tok := p.next()
+	if tok.kind != tokWord {
+		return nil, p.errorf(tok, "expected a field name but found %s", tok)
+	}
+	name := strings.ToLower(tok.text)
+	if alias, ok := fieldAliases[name]; ok {
+		name = alias
+	}
+	f, ok := fields[name]
+	if !ok {
+		return nil, p.errorf(tok, "unknown field %q, use one of: %s", tok.text, strings.Join(Fields(), ", "))
+	}
+	p.used[name] = true
+
+	// A flag on its own tests for true
+	opTok := p.peek()
+	if f.kind == kindFlag && opTok.kind != tokOp {
+		return flagNode{get: f.flag, want: true}, nil
+	}
+
+	op := strings.ToLower(opTok.text)
+	if opTok.kind != tokOp && !opTok.is("matches") && !opTok.is("in") {
+		return nil, p.errorf(opTok, "expected an operator after %s but found %s", name, opTok)
+	}
+	p.next()
+	if !supportsOp(f.kind, op) {
+		return nil, p.errorf(opTok, "operator %s cannot be used with %s", op, name)
+	}
+
+	// != is the negation of ==; for tags it means that no tag is equal
+	negate := op == "!="
+	if negate {
+		op = "=="
+	}
+	n, err := p.parseOperand(f, name, op)
+	if err != nil {
+		return nil, err
+	}
+	if negate {
+		return notNode{n}, nil
+	}
+	return n, nil
+}
+
+// supportsOp reports whether an operator applies to a kind of field.
+func supportsOp(kind fieldKind, op string) bool {
+	switch kind {
+	case kindFlag:
+		return op == "==" || op == "!="
+	case kindText, kindTags, kindAddr:
+		return op == "==" || op == "!=" || op == "matches" || op == "in"
+	case kindTime:
+		return op == "<" || op == "<=" || op == ">" || op == ">="
+	}
+	return false
+}
+
+// parseOperand reads the value or list on the right of op and builds
+// the comparison node.
+func (p *parser) parseOperand(f field, name, op string) (node, error) {
+	valueTok := p.peek()
+	var values []string
+	var err error
+	if op == "in" && valueTok.kind == tokLBracket {
+		values, err = p.parseList()
+	} else {
+		var v string
+		v, err = p.parseValue()
+		values = []string{v}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch f.kind {
+	case kindFlag:
+		switch strings.ToLower(values[0]) {
+		case "true":
+			return flagNode{get: f.flag, want: true}, nil
+		case "false":
+			return flagNode{get: f.flag, want: false}, nil
+		}
+		return nil, p.errorf(valueTok, "%s is compared with true or false", name)
+
+	case kindTime:
+		at, err := p.parseTime(values[0])
+		if err != nil {
+			return nil, p.errorf(valueTok, "%v", err)
+		}
+		return timeNode{get: f.time, op: op, at: at}, nil
+
+	case kindAddr:
+		if op == "matches" {
+			break
+		}
+		if op == "==" && strings.ContainsAny(values[0], "/-") {
+			return nil, p.errorf(valueTok, "use in to compare %s with %s", name, values[0])
+		}
+		set, err := match.ParseAddrSet(strings.Join(values, ","))
+		if err != nil {
+			return nil, p.errorf(valueTok, "%v", err)
+		}
+		return addrNode{addr: f.text, set: set}, nil
+	}
+
+	// Platforms and architectures compare by canonical name
+	if f.canon != nil && op != "matches" {
+		for i, v := range values {
+			name, err := f.canon(v)
+			if err != nil {
+				return nil, p.errorf(valueTok, "%v", err)
+			}
+			values[i] = name
+		}
+	}
+
+	// Text, tags, and address globs
+	test := func(s string) bool {
+		for _, v := range values {
+			if strings.EqualFold(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+	if op == "matches" {
+		re, err := match.Glob(values[0])
+		if err != nil {
+			return nil, p.errorf(valueTok, "%v", err)
+		}
+		test = re.MatchString
+	}
+	return textNode{text: f.text, tags: f.tags, test: test}, nil
+}
+
+// parseValue reads a quoted string or bare word.
+func (p *parser) parseValue() (string, error) {
+	tok := p.next()
+	if tok.kind != tokWord && tok.kind != tokString {
+		return "", p.errorf(tok, "expected a value but found %s", tok)
+	}
+	return tok.text, nil
+}
+
+// parseList reads a bracketed, comma-separated list of values.
+func (p *parser) parseList() ([]string, error) {
+	p.next() // [
+	var values []string
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		tok := p.next()
+		if tok.kind == tokRBracket {
+			return values, nil
+		}
+		if tok.kind != tokComma {
+			return nil, p.errorf(tok, "expected , or ] but found %s", tok)
+		}
+	}
+}
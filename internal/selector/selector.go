@@ -0,0 +1,288 @@
+// Package selector provides a small expression language for selecting
+// sensors, so that every command targets the same sensors for the same
+// expression.
+//
+// An expression combines comparisons with and, or, not and parentheses:
+//
+//	plat == "windows" and tag matches "prod-*" and not online and int_ip in 10.0.0.0/8
+//
+// The supported comparisons depend on the field:
+// - Text fields (sid, hostname, plat, ext_plat, arch, oid, version, mac, did): ==, !=, matches, in [a, b]
+// - tag: the same operators, true if any of the sensor's tags compares true
+// - Flags (online, isolated, sealed, kernel): used alone, or == true / == false
+// - Addresses (int_ip, ext_ip): ==, !=, matches, in CIDR or in [CIDR, range, IP, ...]
+// - Times (last_seen, enrolled): <, <=, >, >= a date, time or age
+//
+// Text comparisons ignore case. matches takes a glob where * matches any
+// run of characters, ? one character and [abc] a character class; the
+// glob must match the whole value. Values are quoted strings or bare
+// words such as windows or 10.0.0.0/8. Platforms and architectures are
+// compared by name, so plat == mac, plat == osx and plat == macOS are the
+// same test, and globs match names such as macos or arm64.
+//
+// Example usage:
+//
+//	sel, err := selector.Parse(`plat == windows and not sealed`, nil)
+//	if sel.Match(sensor) {
+//	    // ...
+//	}
+package selector
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"LC_utils/internal/api"
+	"LC_utils/internal/match"
+)
+
+// Options configure how an expression is parsed.
+type Options struct {
+	// ParseTime parses the values compared with time fields, e.g.
+	// "2026-01-01" or "7d". By default dates and RFC 3339 times in UTC
+	// are accepted.
+	ParseTime func(value string) (time.Time, error)
+}
+
+// Selector is a parsed expression. It is safe for concurrent use.
+type Selector struct {
+	source string
+	root   node
+	used   map[string]bool
+}
+
+// SyntaxError reports an invalid expression.
+type SyntaxError struct {
+	// Column is the 1-based position of the problem in the expression
+	Column int
+	// Msg describes the problem
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid selector at column %d: %s", e.Column, e.Msg)
+}
+
+// Parse parses a selector expression.
+//
+// Parameters:
+//   - expr: The expression, e.g. `plat == windows and online`
+//   - opts: Parsing options, or nil for the defaults
+//
+// Returns:
+//   - *Selector: The parsed expression
+//   - error: A *SyntaxError if the expression is invalid
+func Parse(expr string, opts *Options) (*Selector, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	parseTime := opts.ParseTime
+	if parseTime == nil {
+		parseTime = defaultParseTime
+	}
+
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, parseTime: parseTime, used: map[string]bool{}}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	return &Selector{source: expr, root: root, used: p.used}, nil
+}
+
+// Match reports whether a sensor is selected by the expression.
+func (s *Selector) Match(sensor api.Sensor) bool {
+	return s.root.eval(&sensor)
+}
+
+// Uses reports whether the expression refers to a field, e.g. "tag", so
+// that callers only request the data they need.
+func (s *Selector) Uses(field string) bool {
+	if alias, ok := fieldAliases[field]; ok {
+		field = alias
+	}
+	return s.used[field]
+}
+
+// String returns the expression as it was given.
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Fields returns the names of the fields an expression can use.
+func Fields() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultParseTime accepts dates and RFC 3339 times.
+func defaultParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// fieldKind is the type of a field, which decides its operators.
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindTags
+	kindFlag
+	kindAddr
+	kindTime
+)
+
+// field reads one sensor attribute. Only the getter matching its kind
+// is set.
+type field struct {
+	kind fieldKind
+	text func(*api.Sensor) string
+	// canon converts compared values to the form text returns, e.g.
+	// platform aliases to their name; nil for plain text
+	canon func(string) (string, error)
+	tags  func(*api.Sensor) []string
+	flag  func(*api.Sensor) bool
+	time  func(*api.Sensor) (time.Time, bool)
+}
+
+// fields are the sensor attributes available to expressions.
+var fields = map[string]field{
+	"sid":       {kind: kindText, text: func(s *api.Sensor) string { return s.SID }},
+	"hostname":  {kind: kindText, text: func(s *api.Sensor) string { return s.Hostname }},
+	"plat":      {kind: kindText, text: func(s *api.Sensor) string { return s.PlatformID.Name() }, canon: platformName},
+	"ext_plat":  {kind: kindText, text: func(s *api.Sensor) string { return s.ExternalPlatform.Name() }, canon: platformName},
+	"arch":      {kind: kindText, text: func(s *api.Sensor) string { return s.Architecture.Name() }, canon: archName},
+	"oid":       {kind: kindText, text: func(s *api.Sensor) string { return s.OID }},
+	"version":   {kind: kindText, text: func(s *api.Sensor) string { return s.Version }},
+	"mac":       {kind: kindText, text: func(s *api.Sensor) string { return s.MacAddr }},
+	"did":       {kind: kindText, text: func(s *api.Sensor) string { return s.InstallationID }},
+	"tag":       {kind: kindTags, tags: func(s *api.Sensor) []string { return s.Tags }},
+	"online":    {kind: kindFlag, flag: func(s *api.Sensor) bool { return s.IsOnline }},
+	"isolated":  {kind: kindFlag, flag: func(s *api.Sensor) bool { return s.IsIsolated }},
+	"sealed":    {kind: kindFlag, flag: func(s *api.Sensor) bool { return s.IsSealed }},
+	"kernel":    {kind: kindFlag, flag: func(s *api.Sensor) bool { return s.KernelAvailable }},
+	"int_ip":    {kind: kindAddr, text: func(s *api.Sensor) string { return s.InternalIP }},
+	"ext_ip":    {kind: kindAddr, text: func(s *api.Sensor) string { return s.ExternalIP }},
+	"last_seen": {kind: kindTime, time: func(s *api.Sensor) (time.Time, bool) { return s.LastSeenTime() }},
+	"enrolled":  {kind: kindTime, time: func(s *api.Sensor) (time.Time, bool) { return s.EnrolledTime() }},
+}
+
+// platformName returns the canonical name of a platform, e.g. "macos"
+// for "osx".
+func platformName(value string) (string, error) {
+	p, err := api.ParsePlatform(value)
+	if err != nil {
+		return "", err
+	}
+	return p.Name(), nil
+}
+
+// archName returns the canonical name of an architecture, e.g. "arm64"
+// for "aarch64".
+func archName(value string) (string, error) {
+	a, err := api.ParseArch(value)
+	if err != nil {
+		return "", err
+	}
+	return a.Name(), nil
+}
+
+// fieldAliases are alternative names of fields.
+var fieldAliases = map[string]string{
+	"platform":     "plat",
+	"ext_platform": "ext_plat",
+	"architecture": "arch",
+	"tags":         "tag",
+	"alive":        "last_seen",
+}
+
+// node is an element of a parsed expression.
+type node interface {
+	eval(s *api.Sensor) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(s *api.Sensor) bool { return n.left.eval(s) && n.right.eval(s) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(s *api.Sensor) bool { return n.left.eval(s) || n.right.eval(s) }
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(s *api.Sensor) bool { return !n.operand.eval(s) }
+
+// flagNode tests a flag field against a constant.
+type flagNode struct {
+	get  func(*api.Sensor) bool
+	want bool
+}
+
+func (n flagNode) eval(s *api.Sensor) bool { return n.get(s) == n.want }
+
+// textNode tests a text field, or any of the tags, with test.
+type textNode struct {
+	text func(*api.Sensor) string
+	tags func(*api.Sensor) []string
+	test func(string) bool
+}
+
+func (n textNode) eval(s *api.Sensor) bool {
+	if n.tags == nil {
+		return n.test(n.text(s))
+	}
+	for _, tag := range n.tags(s) {
+		if n.test(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// addrNode tests an address field against a set of addresses. Sensors
+// without a valid address never match.
+type addrNode struct {
+	addr func(*api.Sensor) string
+	set  *match.AddrSet
+}
+
+func (n addrNode) eval(s *api.Sensor) bool { return n.set.Contains(n.addr(s)) }
+
+// timeNode compares a time field with a point in time. Sensors whose
+// time is unknown never match.
+type timeNode struct {
+	get func(*api.Sensor) (time.Time, bool)
+	op  string
+	at  time.Time
+}
+
+func (n timeNode) eval(s *api.Sensor) bool {
+	t, ok := n.get(s)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return t.Before(n.at)
+	case "<=":
+		return !t.After(n.at)
+	case ">":
+		return t.After(n.at)
+	default: // ">="
+		return !t.Before(n.at)
+	}
+}
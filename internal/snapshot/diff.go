@@ -0,0 +1,231 @@
+package snapshot
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"LC_utils/internal/api"
+)
+
+// Diff is the set of changes between two snapshots.
+type Diff struct {
+	From     string    `json:"from"`
+	FromTime time.Time `json:"from_time"`
+	To       string    `json:"to"`
+	ToTime   time.Time `json:"to_time"`
+	// Added are the sensors only in the newer snapshot
+	Added []SensorInfo `json:"added"`
+	// Removed are the sensors only in the older snapshot
+	Removed []SensorInfo `json:"removed"`
+	// Changed are the sensors in both snapshots that differ
+	Changed []Change `json:"changed"`
+}
+
+// SensorInfo identifies a sensor in a diff.
+type SensorInfo struct {
+	OID      string `json:"oid,omitempty"`
+	SID      string `json:"sid"`
+	Hostname string `json:"hostname"`
+	Platform string `json:"platform"`
+}
+
+// Change lists what changed on one sensor. Only the fields that changed
+// are set.
+type Change struct {
+	// Sensor is the sensor as it is in the newer snapshot
+	Sensor      SensorInfo     `json:"sensor"`
+	Hostname    *ValueChange   `json:"hostname,omitempty"`
+	InternalIP  *ValueChange   `json:"int_ip,omitempty"`
+	ExternalIP  *ValueChange   `json:"ext_ip,omitempty"`
+	Version     *VersionChange `json:"version,omitempty"`
+	Isolated    *BoolChange    `json:"isolated,omitempty"`
+	TagsAdded   []string       `json:"tags_added,omitempty"`
+	TagsRemoved []string       `json:"tags_removed,omitempty"`
+}
+
+// ValueChange is a text attribute before and after.
+type ValueChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// VersionChange is a sensor version change.
+type VersionChange struct {
+	ValueChange
+	// Kind is "upgrade", "downgrade", or "change" when the versions
+	// cannot be compared
+	Kind string `json:"kind"`
+}
+
+// BoolChange is a flag before and after.
+type BoolChange struct {
+	From bool `json:"from"`
+	To   bool `json:"to"`
+}
+
+// Empty reports whether the snapshots hold the same sensors with the
+// same attributes.
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Compare returns the changes from one snapshot to a newer one. Sensors
+// are matched by organization and SID, so a renamed sensor is a change
+// while a reinstalled one is a removal and an addition.
+//
+// Parameters:
+//   - from: The older snapshot
+//   - to: The newer snapshot
+//
+// Returns:
+//   - *Diff: The changes, sorted by organization, hostname and SID
+func Compare(from, to *Snapshot) *Diff {
+	d := &Diff{
+		From:     from.Name,
+		FromTime: from.CreatedAt,
+		To:       to.Name,
+		ToTime:   to.CreatedAt,
+		Added:    []SensorInfo{},
+		Removed:  []SensorInfo{},
+		Changed:  []Change{},
+	}
+
+	old := map[string]*api.Sensor{}
+	for i := range from.Sensors {
+		old[sensorKey(&from.Sensors[i])] = &from.Sensors[i]
+	}
+	seen := map[string]bool{}
+	for i := range to.Sensors {
+		s := &to.Sensors[i]
+		key := sensorKey(s)
+		seen[key] = true
+		prev, ok := old[key]
+		if !ok {
+			d.Added = append(d.Added, infoOf(s))
+			continue
+		}
+		if c, changed := compareSensor(prev, s); changed {
+			d.Changed = append(d.Changed, c)
+		}
+	}
+	for i := range from.Sensors {
+		if s := &from.Sensors[i]; !seen[sensorKey(s)] {
+			d.Removed = append(d.Removed, infoOf(s))
+		}
+	}
+
+	sortInfos(d.Added)
+	sortInfos(d.Removed)
+	sort.Slice(d.Changed, func(i, j int) bool { return infoLess(d.Changed[i].Sensor, d.Changed[j].Sensor) })
+	return d
+}
+
+// compareSensor returns the changes between two versions of a sensor.
+func compareSensor(prev, cur *api.Sensor) (Change, bool) {
+	c := Change{Sensor: infoOf(cur)}
+	changed := false
+	text := func(before, after string) *ValueChange {
+		if before == after {
+			return nil
+		}
+		changed = true
+		return &ValueChange{From: before, To: after}
+	}
+	c.Hostname = text(prev.Hostname, cur.Hostname)
+	c.InternalIP = text(prev.InternalIP, cur.InternalIP)
+	c.ExternalIP = text(prev.ExternalIP, cur.ExternalIP)
+	if v := text(prev.Version, cur.Version); v != nil {
+		c.Version = &VersionChange{ValueChange: *v, Kind: versionChangeKind(prev.Version, cur.Version)}
+	}
+	if prev.IsIsolated != cur.IsIsolated {
+		changed = true
+		c.Isolated = &BoolChange{From: prev.IsIsolated, To: cur.IsIsolated}
+	}
+	c.TagsAdded = missingFrom(cur.Tags, prev.Tags)
+	c.TagsRemoved = missingFrom(prev.Tags, cur.Tags)
+	if len(c.TagsAdded) > 0 || len(c.TagsRemoved) > 0 {
+		changed = true
+	}
+	return c, changed
+}
+
+// versionChangeKind compares versions such as "4.29.1" number by number.
+func versionChangeKind(before, after string) string {
+	a, okA := versionNumbers(before)
+	b, okB := versionNumbers(after)
+	if !okA || !okB {
+		return "change"
+	}
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case y > x:
+			return "upgrade"
+		case y < x:
+			return "downgrade"
+		}
+	}
+	return "change"
+}
+
+// versionNumbers splits a version into its numbers, ignoring a leading v.
+func versionNumbers(v string) ([]int, bool) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(v), "v"), ".")
+	numbers := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		numbers[i] = n
+	}
+	return numbers, true
+}
+
+// missingFrom returns the values of a that are not in b, sorted.
+func missingFrom(a, b []string) []string {
+	in := map[string]bool{}
+	for _, v := range b {
+		in[v] = true
+	}
+	var missing []string
+	for _, v := range a {
+		if !in[v] {
+			missing = append(missing, v)
+			in[v] = true
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// sensorKey identifies a sensor across snapshots.
+func sensorKey(s *api.Sensor) string {
+	return strings.ToLower(s.OID) + "/" + strings.ToLower(s.SID)
+}
+
+func infoOf(s *api.Sensor) SensorInfo {
+	return SensorInfo{OID: s.OID, SID: s.SID, Hostname: s.Hostname, Platform: s.GetPlatformString()}
+}
+
+func sortInfos(infos []SensorInfo) {
+	sort.Slice(infos, func(i, j int) bool { return infoLess(infos[i], infos[j]) })
+}
+
+func infoLess(a, b SensorInfo) bool {
+	if a.OID != b.OID {
+		return a.OID < b.OID
+	}
+	if ha, hb := strings.ToLower(a.Hostname), strings.ToLower(b.Hostname); ha != hb {
+		return ha < hb
+	}
+	return a.SID < b.SID
+}
@@ -0,0 +1,248 @@
+// Package snapshot provides sensor inventory snapshots: the full sensor
+// list of one or more organizations saved to a local file, and the
+// differences between two snapshots.
+//
+// Snapshots are JSON files named after the snapshot in a directory,
+// ~/.local/share/lc-sensors/snapshots by default. Each file records the
+// version of its format so that older snapshots stay readable as the
+// format evolves.
+//
+// Example usage:
+//
+//	path, err := snapshot.Save(snapshot.DefaultDir(), snap, false)
+//	old, err := snapshot.Load(path)
+//	diff := snapshot.Compare(old, snap)
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"LC_utils/internal/api"
+)
+
+// FormatVersion is the version of the snapshot file format written by
+// this package. Files with a higher version are refused.
+const FormatVersion = 1
+
+// Latest is the reference to the most recent snapshot in a directory.
+const Latest = "latest"
+
+// ErrNotFound is returned when a referenced snapshot does not exist.
+var ErrNotFound = errors.New("snapshot not found")
+
+// Snapshot is the sensor inventory at one point in time.
+type Snapshot struct {
+	// FormatVersion is the version of the file format
+	FormatVersion int `json:"format_version"`
+	// Name identifies the snapshot; it is also its file name
+	Name string `json:"name"`
+	// CreatedAt is when the sensors were listed
+	CreatedAt time.Time `json:"created_at"`
+	// Orgs are the OIDs of the organizations listed
+	Orgs []string `json:"orgs"`
+	// Sensors are the sensors of every organization, with their tags
+	Sensors []api.Sensor `json:"sensors"`
+}
+
+// Info describes a saved snapshot.
+type Info struct {
+	Name      string
+	Path      string
+	CreatedAt time.Time
+	Orgs      int
+	Sensors   int
+}
+
+// DefaultDir returns the snapshot directory. LC_SENSORS_SNAPSHOTS
+// overrides it; otherwise it is lc-sensors/snapshots under
+// $XDG_DATA_HOME or ~/.local/share.
+func DefaultDir() string {
+	if dir := os.Getenv("LC_SENSORS_SNAPSHOTS"); dir != "" {
+		return dir
+	}
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "lc-sensors", "snapshots")
+}
+
+// DefaultName returns the name of a snapshot taken at t when none is
+// given, e.g. "2026-10-16T091500Z".
+func DefaultName(t time.Time) string {
+	return t.UTC().Format("2006-01-02T150405Z")
+}
+
+// ValidateName checks that a snapshot name can be used as a file name:
+// letters, digits, dots, dashes and underscores, not starting with a dot.
+func ValidateName(name string) error {
+	if name == "" || name == Latest || name == "live" {
+		return fmt.Errorf("invalid snapshot name %q", name)
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		case r == '.' && i > 0:
+		default:
+			return fmt.Errorf("invalid snapshot name %q: use letters, digits, dots, dashes and underscores", name)
+		}
+	}
+	return nil
+}
+
+// Save writes a snapshot to dir as NAME.json, creating dir. The file is
+// only readable by the owner, as it describes the organization's hosts.
+//
+// Parameters:
+//   - dir: The snapshot directory
+//   - s: The snapshot; its FormatVersion is set
+//   - force: Replace an existing snapshot with the same name
+//
+// Returns:
+//   - string: The path of the file written
+//   - error: Any error writing the file, or an existing snapshot
+func Save(dir string, s *Snapshot, force bool) (string, error) {
+	if err := ValidateName(s.Name); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, s.Name+".json")
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("snapshot %q already exists in %s, use another name or --force", s.Name, dir)
+	}
+
+	s.FormatVersion = FormatVersion
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding snapshot: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("error creating snapshot directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed write never leaves a
+	// truncated snapshot behind
+	tmp, err := os.CreateTemp(dir, ".snapshot-*.json")
+	if err != nil {
+		return "", fmt.Errorf("error writing snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("error writing snapshot: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("error writing snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("error writing snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("error writing snapshot: %w", err)
+	}
+	return path, nil
+}
+
+// Load reads a snapshot file.
+//
+// Parameters:
+//   - path: The snapshot file
+//
+// Returns:
+//   - *Snapshot: The snapshot
+//   - error: Any error reading or decoding the file, or a format newer
+//     than FormatVersion
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return nil, fmt.Errorf("error reading snapshot: %w", err)
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error decoding snapshot %s: %w", path, err)
+	}
+	if s.FormatVersion < 1 || s.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("snapshot %s has format version %d, this version of lc-sensors reads up to %d", path, s.FormatVersion, FormatVersion)
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	return &s, nil
+}
+
+// Resolve returns the path of a snapshot reference: a file path, the
+// name of a snapshot in dir, or Latest for the most recent one.
+//
+// Parameters:
+//   - dir: The snapshot directory
+//   - ref: The reference
+//
+// Returns:
+//   - string: The snapshot file
+//   - error: ErrNotFound if nothing matches
+func Resolve(dir, ref string) (string, error) {
+	if ref == Latest {
+		infos, err := List(dir)
+		if err != nil {
+			return "", err
+		}
+		if len(infos) == 0 {
+			return "", fmt.Errorf("%w: no snapshots in %s", ErrNotFound, dir)
+		}
+		return infos[len(infos)-1].Path, nil
+	}
+	if strings.ContainsAny(ref, `/\`) || strings.HasSuffix(ref, ".json") {
+		if _, err := os.Stat(ref); err == nil {
+			return ref, nil
+		}
+	}
+	path := filepath.Join(dir, ref+".json")
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%w: %q is neither a file nor a snapshot in %s", ErrNotFound, ref, dir)
+	}
+	return path, nil
+}
+
+// List returns the snapshots saved in dir, oldest first. A missing
+// directory holds no snapshots; unreadable files are skipped.
+func List(dir string) ([]Info, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var infos []Info
+	for _, path := range paths {
+		s, err := Load(path)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, Info{
+			Name:      s.Name,
+			Path:      path,
+			CreatedAt: s.CreatedAt,
+			Orgs:      len(s.Orgs),
+			Sensors:   len(s.Sensors),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].CreatedAt.Equal(infos[j].CreatedAt) {
+			return infos[i].CreatedAt.Before(infos[j].CreatedAt)
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}